@@ -0,0 +1,31 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// canWrite probes whether the key may modify the zone by sending an ADDORUPDATERR request without records,
+// which changes nothing. A denial is returned as an error wrapping ErrAuthFailed together with false.
+func (p *Provider) canWrite(ctx context.Context, ddnsKey string, zoneName string) (bool, error) {
+	_, err := p.do(ctx, Zone{
+		Name:    zoneName,
+		Action:  actionAddOrUpdateRR,
+		DDNSKey: ddnsKey,
+	})
+	if err == nil {
+		return true, nil
+	}
+
+	// only a status of the robot is an answer to the probe, HTTP and network failures are not
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusOK {
+		return false, err
+	}
+	if errors.Is(apiErr, ErrAuthFailed) {
+		return false, apiErr
+	}
+	return false, fmt.Errorf("write probe inconclusive: %w", apiErr)
+}
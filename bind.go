@@ -0,0 +1,82 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxCharacterString is the maximum length of a single character-string in a TXT record.
+const maxCharacterString = 255
+
+// exportBIND renders the zone in the RFC 1035 master file format.
+// The robot does not export the SOA MNAME and RNAME, so the first apex NS record and hostmaster@<zone>
+// are used instead; the serial is derived from the current time. Values other than TXT are written as
+// returned by the robot.
+func (p *Provider) exportBIND(ctx context.Context, ddnsKey string, zoneName string) ([]byte, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	origin := strings.TrimSuffix(zoneName, ".") + "."
+	mname := origin
+	for _, record := range zoneExport.records {
+		if strings.EqualFold(record.Type, "NS") && bindName(record.Host) == "@" {
+			mname = record.Value
+			break
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(&b, "$TTL %d\n", zoneExport.ttl)
+	fmt.Fprintf(&b, "@\tIN\tSOA\t%s hostmaster.%s (\n", mname, origin)
+	fmt.Fprintf(&b, "\t\t%s ; serial\n", p.now().UTC().Format("2006010215"))
+	fmt.Fprintf(&b, "\t\t%d ; refresh\n", zoneExport.soa.Refresh)
+	fmt.Fprintf(&b, "\t\t%d ; retry\n", zoneExport.soa.Retry)
+	fmt.Fprintf(&b, "\t\t%d ; expire\n", zoneExport.soa.Expire)
+	fmt.Fprintf(&b, "\t\t%d ) ; minimum\n", zoneExport.soa.MTTL)
+
+	for _, record := range zoneExport.records {
+		ttl := zoneExport.ttl
+		if record.TTL > 0 {
+			ttl = record.TTL
+		}
+		fmt.Fprintf(&b, "%s\t%d\t%s\t%s\t%s\n", bindName(record.Host), ttl, record.RecordClass(), strings.ToUpper(record.Type), bindValue(record.Type, record.Value))
+	}
+
+	return b.Bytes(), nil
+}
+
+// bindName returns the owner name of a record in the master file, using @ for the apex.
+func bindName(host string) string {
+	if host == "" {
+		return "@"
+	}
+	return host
+}
+
+// bindValue returns the RDATA of a record in the master file. TXT values are quoted and split into
+// character-strings of at most 255 bytes, unless they are quoted already.
+func bindValue(rtype string, value string) string {
+	if !strings.EqualFold(rtype, "TXT") || strings.HasPrefix(value, `"`) {
+		return value
+	}
+
+	var parts []string
+	for len(parts) == 0 || value != "" {
+		n := min(len(value), maxCharacterString)
+		parts = append(parts, quoteCharacterString(value[:n]))
+		value = value[n:]
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteCharacterString quotes s as a character-string, escaping quotes and backslashes.
+func quoteCharacterString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
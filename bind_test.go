@@ -0,0 +1,48 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestExportBIND(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	p, _ := newTestProvider(t, robottest.Exchange{Action: actionGetZone, Response: `<zoneRequest status="ok"><zone name="example.com">` +
+		`<soa refresh="86400" retry="7200" expire="3600000" mttl="3600"></soa>` +
+		`<rr host="@" type="NS" value="ns1.s-dns.de."></rr>` +
+		`<rr host="@" type="MX" value="10 mail.example.com."></rr>` +
+		`<rr host="www" type="A" value="192.0.2.1" ttl="600"></rr>` +
+		`<rr host="_acme-challenge" type="TXT" value="token with &quot;quotes&quot; and \ backslash"></rr>` +
+		`<rr host="long" type="txt" value="` + long + `"></rr>` +
+		`<rr host="quoted" type="TXT" value="&quot;already quoted&quot;"></rr>` +
+		`</zone></zoneRequest>`,
+	})
+	p.clock = newFakeClock()
+
+	got, err := p.ExportBIND(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("ExportBIND() error = %v", err)
+	}
+
+	golden := filepath.Join("testdata", "example.com.zone")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("ExportBIND() =\n%s\nwant\n%s", got, want)
+	}
+}
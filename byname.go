@@ -0,0 +1,96 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// getRecordsByName returns the records of a name, whatever their type. A cached zone is filtered locally;
+// otherwise the zone response is decoded as a stream, keeping only the records of the name, so large zones
+// are never converted as a whole. A name without records yields an empty result, not an error; a response
+// status other than ok is checked before and fails with an *APIError.
+func (p *Provider) getRecordsByName(ctx context.Context, ddnsKey string, zoneName string, name string) ([]libdns.Record, error) {
+	name = relativeHost(asciiName(name), zoneName)
+
+	if p.ZoneCacheTTL > 0 {
+		zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+		if err != nil {
+			return nil, err
+		}
+		zoneExport.records = filterName(zoneExport.records, name)
+		return p.libdnsRecords(zoneExport), nil
+	}
+
+	body, err := p.fetchZone(ctx, ddnsKey, zoneName, "")
+	if err != nil {
+		return nil, err
+	}
+	records, mttl, err := decodeName(body, zoneName, name)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling XML response: %v", err)
+	}
+	ttl, err := p.zoneTTL(ctx, zoneName, mttl)
+	if err != nil {
+		return nil, err
+	}
+	return p.libdnsRecords(ZoneExport{records: records, ttl: ttl}), nil
+}
+
+// decodeName decodes the records of a name from a zone response, with hosts relative to the zone, along
+// with the MTTL of the zone. Other records are skipped without being decoded.
+func decodeName(body []byte, zoneName string, name string) (records []ResourceRecord, mttl int, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(trimXMLPrefix(body)))
+	decoder.CharsetReader = charsetReader
+
+	soaSeen := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return records, mttl, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "soa":
+			// the SOA of the root element comes first and takes precedence, like in decodeZoneResponse
+			var soa SOA
+			if err := decoder.DecodeElement(&soa, &start); err != nil {
+				return nil, 0, err
+			}
+			if !soaSeen {
+				mttl, soaSeen = clampTTL(soa.MTTL), true
+			}
+		case "rr":
+			var record ResourceRecord
+			if err := decoder.DecodeElement(&record, &start); err != nil {
+				return nil, 0, err
+			}
+			record.Host = relativeHost(record.Host, zoneName)
+			if strings.EqualFold(record.Host, name) {
+				records = append(records, record)
+			}
+		}
+	}
+}
+
+// filterName returns the records of the given host.
+func filterName(records []ResourceRecord, host string) []ResourceRecord {
+	var filtered []ResourceRecord
+	for _, record := range records {
+		if strings.EqualFold(record.Host, host) {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
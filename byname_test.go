@@ -0,0 +1,43 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetRecordsByName(t *testing.T) {
+	for _, cacheTTL := range []time.Duration{0, time.Minute} {
+		p, _ := newTestProvider(t, fixture(t, "getzone"))
+		p.ZoneCacheTTL = cacheTTL
+
+		records, err := p.GetRecordsByName(context.Background(), testZone, "WWW")
+		if err != nil {
+			t.Fatalf("GetRecordsByName() with cache TTL %v error = %v", cacheTTL, err)
+		}
+		var types []string
+		for _, record := range records {
+			rr := record.RR()
+			if rr.Name != "www" || rr.TTL != time.Hour {
+				t.Errorf("GetRecordsByName() with cache TTL %v returned %v", cacheTTL, rr)
+			}
+			types = append(types, rr.Type)
+		}
+		if len(types) != 2 || types[0] != "A" || types[1] != "AAAA" {
+			t.Errorf("GetRecordsByName() with cache TTL %v returned types %v, want [A AAAA]", cacheTTL, types)
+		}
+	}
+}
+
+func TestGetRecordsByNameDenied(t *testing.T) {
+	p, _ := newTestProvider(t, fixture(t, "getzone-denied"))
+
+	records, err := p.GetRecordsByName(context.Background(), testZone, "www")
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("GetRecordsByName() error = %v, want %v", err, ErrAuthFailed)
+	}
+	if len(records) != 0 {
+		t.Errorf("GetRecordsByName() returned %d records along with the error", len(records))
+	}
+}
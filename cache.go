@@ -0,0 +1,77 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"time"
+)
+
+// zoneCacheKey identifies a cached zone export. The key is part of it, since different DDNS keys
+// may see different zones.
+type zoneCacheKey struct {
+	ddnsKey  string
+	zoneName string
+}
+
+// cachedZone is a zone export together with the time it stops being fresh.
+type cachedZone struct {
+	export  ZoneExport
+	expires time.Time
+}
+
+// zone returns the export of the zone. If ZoneCacheTTL is set, a snapshot fetched within the TTL is
+// reused instead of sending another GETZONE request.
+func (p *Provider) zone(ctx context.Context, ddnsKey string, zoneName string) (ZoneExport, error) {
+	if p.ZoneCacheTTL <= 0 {
+		return p.getZone(ctx, ddnsKey, zoneName)
+	}
+
+	key := zoneCacheKey{ddnsKey: ddnsKey, zoneName: zoneName}
+	p.cacheMu.Lock()
+	entry, ok := p.zoneCache[key]
+	p.cacheMu.Unlock()
+	if ok && p.now().Before(entry.expires) {
+		return entry.export, nil
+	}
+
+	export, err := p.getZone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return ZoneExport{}, err
+	}
+
+	// a zone fetched while the key was rotated is not kept for the rejected key
+	if p.rotatedOut(ctx, ddnsKey) {
+		return export, nil
+	}
+
+	p.cacheMu.Lock()
+	if p.zoneCache == nil {
+		p.zoneCache = make(map[zoneCacheKey]cachedZone)
+	}
+	p.zoneCache[key] = cachedZone{export: export, expires: p.now().Add(p.ZoneCacheTTL)}
+	p.cacheMu.Unlock()
+
+	return export, nil
+}
+
+// invalidateZone drops the cached export of the zone. It is called after every successful write.
+func (p *Provider) invalidateZone(ddnsKey string, zoneName string) {
+	p.cacheMu.Lock()
+	delete(p.zoneCache, zoneCacheKey{ddnsKey: ddnsKey, zoneName: zoneName})
+	p.cacheMu.Unlock()
+}
+
+// invalidateKey drops the cached exports and root zones of all zones of a key, e.g. once it is rotated out.
+func (p *Provider) invalidateKey(ddnsKey string) {
+	p.cacheMu.Lock()
+	for key := range p.zoneCache {
+		if key.ddnsKey == ddnsKey {
+			delete(p.zoneCache, key)
+		}
+	}
+	for key := range p.rootZones {
+		if key.ddnsKey == ddnsKey {
+			delete(p.rootZones, key)
+		}
+	}
+	p.cacheMu.Unlock()
+}
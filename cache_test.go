@@ -0,0 +1,69 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// count returns how often action occurs in actions.
+func count(actions []string, action string) int {
+	n := 0
+	for _, a := range actions {
+		if a == action {
+			n++
+		}
+	}
+	return n
+}
+
+func TestZoneCacheReducesRequests(t *testing.T) {
+	ctx := context.Background()
+	p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"))
+	p.ZoneCacheTTL = time.Minute
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.GetRecords(ctx, testZone); err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+	}
+	if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}}); err != nil {
+		t.Fatalf("AppendRecords() error = %v", err)
+	}
+	if got := count(actions(server), actionGetZone); got != 1 {
+		t.Errorf("sent %d GETZONE requests for three reads and an append, want 1", got)
+	}
+
+	// the write invalidates the snapshot
+	if _, err := p.GetRecords(ctx, testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if got := count(actions(server), actionGetZone); got != 2 {
+		t.Errorf("sent %d GETZONE requests after the write, want 2", got)
+	}
+
+	if err := p.RefreshZone(ctx, testZone); err != nil {
+		t.Fatalf("RefreshZone() error = %v", err)
+	}
+	if _, err := p.GetRecords(ctx, testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if got := count(actions(server), actionGetZone); got != 3 {
+		t.Errorf("sent %d GETZONE requests after RefreshZone, want 3", got)
+	}
+}
+
+func TestZoneCacheDisabled(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"))
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+	}
+	if got := count(actions(server), actionGetZone); got != 2 {
+		t.Errorf("sent %d GETZONE requests without cache, want 2", got)
+	}
+}
@@ -0,0 +1,35 @@
+package libdns_kyberio
+
+// Capabilities describes the operations supported by the provider, so generic tooling can enable or
+// disable features accordingly.
+type Capabilities struct {
+	GetRecords     bool // GetRecords
+	AppendRecords  bool // AppendRecords
+	SetRecords     bool // SetRecords
+	DeleteRecords  bool // DeleteRecords, including deletion by name and type
+	MultiZone      bool // AddOrUpdateZones
+	RootZoneLookup bool // GetRootZone and zone resolution
+	ZoneTTL        bool // Changing the zone TTL; the robot has no action to change the SOA
+	SOATimers      bool // Changing the refresh, retry and expire timers; GetSOA reads them either way
+	BINDExport     bool // ExportBIND
+	PerRecordTTL   bool // TTLs set per record instead of per zone
+	DNSSEC         bool // DS and DNSKEY records
+	ZoneCreate     bool // Creating zones
+	ListZones      bool // Listing the zones of a key
+	DisableRecords bool // Disabling records without deleting them; the robot has no such state
+}
+
+// Capabilities returns the operations supported by the provider.
+func (p *Provider) Capabilities() Capabilities {
+	return Capabilities{
+		GetRecords:     true,
+		AppendRecords:  true,
+		SetRecords:     true,
+		DeleteRecords:  true,
+		MultiZone:      true,
+		RootZoneLookup: true,
+		BINDExport:     true,
+		PerRecordTTL:   true,
+		DNSSEC:         true,
+	}
+}
@@ -0,0 +1,19 @@
+package libdns_kyberio
+
+import "testing"
+
+func TestCapabilities(t *testing.T) {
+	caps := (&Provider{}).Capabilities()
+	if !caps.PerRecordTTL {
+		t.Error("Capabilities() does not report per-record TTLs, which every write sends")
+	}
+	if caps.ZoneTTL || caps.SOATimers {
+		t.Error("Capabilities() reports SOA changes, which the robot has no action for")
+	}
+	if caps.DisableRecords {
+		t.Error("Capabilities() reports disabling records, which the robot has no state for")
+	}
+	if caps.ZoneCreate {
+		t.Error("Capabilities() reports zone creation, which the robot has no action for")
+	}
+}
@@ -0,0 +1,70 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// escapeNonASCII replaces every non-ASCII character of a marshaled request with a character reference.
+// encoding/xml writes UTF-8, while requests declare ISO-8859-1, so characters like ä in a TXT value would
+// otherwise reach the robot garbled. The result reads the same in either encoding.
+func escapeNonASCII(data []byte) []byte {
+	var b bytes.Buffer
+	b.Grow(len(data))
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r < utf8.RuneSelf {
+			b.WriteByte(data[0])
+		} else {
+			fmt.Fprintf(&b, "&#x%X;", r)
+		}
+		data = data[size:]
+	}
+	return b.Bytes()
+}
+
+// unmarshalXML decodes a response body into v. A leading byte order mark or whitespace is ignored, and
+// responses declaring ISO-8859-1 are converted to UTF-8 before decoding.
+func unmarshalXML(body []byte, v any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(trimXMLPrefix(body)))
+	decoder.CharsetReader = charsetReader
+	return decoder.Decode(v)
+}
+
+// charsetReader returns a reader converting input in the given charset to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1":
+		return &latin1Reader{r: input}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+// latin1Reader converts ISO-8859-1 input to UTF-8. Every input byte is the code point of the same value.
+type latin1Reader struct {
+	r       io.Reader
+	pending []byte
+}
+
+// Read implements io.Reader.
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		buf := make([]byte, max(len(p)/2, 1))
+		n, err := l.r.Read(buf)
+		for _, c := range buf[:n] {
+			l.pending = utf8.AppendRune(l.pending, rune(c))
+		}
+		if len(l.pending) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,199 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Default connection settings used when the corresponding Provider field is left empty.
+const (
+	defaultTimeout               = 30 * time.Second
+	defaultDialTimeout           = 10 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultResponseHeaderTimeout = 30 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultMaxRequestBytes       = 1 << 20
+	defaultMaxResponseBytes      = 64 << 20
+	defaultExpectContinueTimeout = 1 * time.Second
+	defaultContentType           = "application/xml"
+)
+
+// expectContinueMinBytes is the body size from which requests carry Expect: 100-continue if ExpectContinue
+// is set. Smaller bodies are cheaper to send than the extra round trip.
+const expectContinueMinBytes = 64 << 10
+
+// endpoint returns the URL of the robot.
+func (p *Provider) endpoint() string {
+	if p.Endpoint != "" {
+		return p.Endpoint
+	}
+	return defaultEndpoint
+}
+
+// contentType returns the Content-Type header of requests.
+func (p *Provider) contentType() string {
+	if p.ContentType != "" {
+		return p.ContentType
+	}
+	return defaultContentType
+}
+
+// rootZoneEndpoint returns the URL for getRootZone lookups.
+func (p *Provider) rootZoneEndpoint() string {
+	if p.RootZoneEndpoint != "" {
+		return p.RootZoneEndpoint
+	}
+	return p.endpoint()
+}
+
+// httpClient returns the HTTP client shared by all requests of the provider.
+// Unless HTTPClient is set, it is built on first use from the connection and TLS settings of the provider.
+func (p *Provider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	p.clientOnce.Do(func() {
+		p.client = p.newHTTPClient()
+	})
+	return p.client
+}
+
+// newHTTPClient builds an HTTP client from the connection and TLS settings of the provider.
+// Certificates are always verified unless the caller explicitly disables it in TLSConfig.
+func (p *Provider) newHTTPClient() *http.Client {
+	var tlsConfig *tls.Config
+	if p.TLSConfig != nil {
+		tlsConfig = p.TLSConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   durationOrDefault(p.DialTimeout, defaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   durationOrDefault(p.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: durationOrDefault(p.ResponseHeaderTimeout, defaultResponseHeaderTimeout),
+		IdleConnTimeout:       durationOrDefault(p.IdleConnTimeout, defaultIdleConnTimeout),
+		DisableKeepAlives:     p.DisableKeepAlives,
+		ForceAttemptHTTP2:     true,
+	}
+	if p.ExpectContinue {
+		transport.ExpectContinueTimeout = defaultExpectContinueTimeout
+	}
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       durationOrDefault(p.Timeout, defaultTimeout),
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// maxRedirects is the number of redirects followed for a single request.
+const maxRedirects = 10
+
+// checkRedirect follows redirects that keep the method and body of a request, 307 and 308, which the client
+// resends with the same body. Other redirects would turn a POST into a body-less GET, silently dropping the
+// records of a write, so they fail with an error wrapping ErrUnexpectedStatusCode instead.
+func checkRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrUnexpectedStatusCode, len(via))
+	}
+	if original := via[0]; request.Method != original.Method {
+		status := 0
+		if request.Response != nil {
+			status = request.Response.StatusCode
+		}
+		return fmt.Errorf("%w: robot redirected the %s request to %s with status %d, which would drop the request body", ErrUnexpectedStatusCode, original.Method, request.URL.Redacted(), status)
+	}
+	return nil
+}
+
+// readBody reads a response body of at most limit bytes. Unlike io.ReadAll alone, it aborts as soon as ctx
+// is done, even if the server stalls in the middle of the body, and returns the context error in that case.
+// A longer body is not read any further and fails with an error wrapping ErrResponseTooLarge.
+func readBody(ctx context.Context, body io.ReadCloser, limit int64) ([]byte, error) {
+	stop := context.AfterFunc(ctx, func() {
+		body.Close()
+	})
+	defer stop()
+
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: body exceeds the limit of %d bytes", ErrResponseTooLarge, limit)
+	}
+	return data, nil
+}
+
+// maxResponseBytes returns the size limit of response bodies.
+func (p *Provider) maxResponseBytes() int64 {
+	if p.MaxResponseBytes > 0 {
+		return int64(p.MaxResponseBytes)
+	}
+	return defaultMaxResponseBytes
+}
+
+// checkRequestSize returns an error wrapping ErrRequestTooLarge if the body of a request exceeds
+// MaxRequestBytes, so oversized batches fail before they are sent.
+func (p *Provider) checkRequestSize(action string, body []byte) error {
+	limit := p.MaxRequestBytes
+	if limit <= 0 {
+		limit = defaultMaxRequestBytes
+	}
+	if len(body) > limit {
+		return fmt.Errorf("%w: %s request is %d bytes, the limit is %d; split the records into smaller batches", ErrRequestTooLarge, action, len(body), limit)
+	}
+	return nil
+}
+
+// durationOrDefault returns d, or fallback if d is not set.
+func durationOrDefault(d time.Duration, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// marshalXML marshals a request compactly, or indented for readability while the logger of the provider
+// records debug messages.
+func (p *Provider) marshalXML(ctx context.Context, v any) ([]byte, error) {
+	if p.Logger != nil && p.Logger.Enabled(ctx, slog.LevelDebug) {
+		return xml.MarshalIndent(v, "", "  ")
+	}
+	return xml.Marshal(v)
+}
+
+// debug logs a diagnostic message if the provider has a logger.
+func (p *Provider) debug(ctx context.Context, msg string, args ...any) {
+	if p.Logger != nil {
+		p.Logger.DebugContext(ctx, msg, args...)
+	}
+}
+
+// warn logs a message about a likely misconfiguration if the provider has a logger.
+func (p *Provider) warn(ctx context.Context, msg string, args ...any) {
+	if p.Logger != nil {
+		p.Logger.WarnContext(ctx, msg, args...)
+	}
+}
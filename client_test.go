@@ -0,0 +1,307 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestStalledResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+	p := &Provider{APIToken: "test-key", Endpoint: server.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := p.GetRecords(ctx, testZone)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetRecords() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("GetRecords() returned after %v, want it to abort at the deadline", elapsed)
+	}
+}
+
+// newRedirectingRobot starts a server redirecting requests to / with status to /robot, where the write is
+// answered. It returns the server and the bodies received at /robot, along with their methods.
+func newRedirectingRobot(t *testing.T, status int) (*httptest.Server, *[]string) {
+	t.Helper()
+	response := fixture(t, "addorupdaterr").Response
+	var mu sync.Mutex
+	var received []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/robot", status)
+	})
+	mux.HandleFunc("/robot", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r.Method+" "+string(body))
+		mu.Unlock()
+		w.Write([]byte(response))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func TestWriteFollowsTemporaryRedirect(t *testing.T) {
+	server, received := newRedirectingRobot(t, http.StatusTemporaryRedirect)
+	p := &Provider{APIToken: "test-key", Endpoint: server.URL + "/"}
+
+	_, err := p.AddOrUpdateResourceRecords(context.Background(), testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.2", KeepExisting: true}})
+	if err != nil {
+		t.Fatalf("AddOrUpdateResourceRecords() error = %v", err)
+	}
+	if len(*received) != 1 || !strings.HasPrefix((*received)[0], "POST ") || !strings.Contains((*received)[0], `value="192.0.2.2"`) {
+		t.Errorf("redirect target received %q, want the POST with its records", *received)
+	}
+}
+
+func TestWriteRefusesMethodChangingRedirect(t *testing.T) {
+	server, received := newRedirectingRobot(t, http.StatusFound)
+	p := &Provider{APIToken: "test-key", Endpoint: server.URL + "/"}
+
+	_, err := p.AddOrUpdateResourceRecords(context.Background(), testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.2", KeepExisting: true}})
+	if !errors.Is(err, ErrUnexpectedStatusCode) {
+		t.Fatalf("AddOrUpdateResourceRecords() error = %v, want %v", err, ErrUnexpectedStatusCode)
+	}
+	if len(*received) != 0 {
+		t.Errorf("redirect target received %q, want nothing", *received)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	export := fixture(t, "getzone").Response
+	for _, test := range []struct {
+		name    string
+		limit   int
+		wantErr error
+	}{
+		{"below the limit", len(export), nil},
+		{"above the limit", len(export) - 1, ErrResponseTooLarge},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, fixture(t, "getzone"))
+			p.MaxResponseBytes = test.limit
+			p.MaxRetries = 2
+
+			_, err := p.GetRecords(context.Background(), testZone)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("GetRecords() error = %v, want %v", err, test.wantErr)
+			}
+			if got := len(server.Requests()); got != 1 {
+				t.Errorf("sent %d requests, want 1 without retries", got)
+			}
+		})
+	}
+}
+
+func TestMaxResponseBytesDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// an endless body
+		chunk := []byte(strings.Repeat(" ", 1<<16))
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	p := &Provider{APIToken: "test-key", Endpoint: server.URL}
+
+	if _, err := p.GetRecords(context.Background(), testZone); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("GetRecords() error = %v, want %v", err, ErrResponseTooLarge)
+	}
+}
+
+// receivedRequest is a request as the robot received it.
+type receivedRequest struct {
+	action           string
+	header           http.Header
+	contentLength    int64
+	transferEncoding []string
+	body             []byte
+}
+
+// newInspectingRobot returns a provider talking to a server that records the requests it receives, as seen
+// on the wire, and answers them from a robottest.Server replaying the given exchanges. If reject is set,
+// requests it matches are answered with its status code instead, without reading the body.
+func newInspectingRobot(t *testing.T, reject func(r *http.Request) int, exchanges ...robottest.Exchange) (*Provider, func() []receivedRequest) {
+	t.Helper()
+	robot := robottest.NewServer(exchanges...)
+	t.Cleanup(robot.Close)
+
+	var mu sync.Mutex
+	var received []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := receivedRequest{header: r.Header.Clone(), contentLength: r.ContentLength, transferEncoding: r.TransferEncoding}
+		if reject != nil {
+			if status := reject(r); status != 0 {
+				mu.Lock()
+				received = append(received, request)
+				mu.Unlock()
+				w.WriteHeader(status)
+				return
+			}
+		}
+		request.body, _ = io.ReadAll(r.Body)
+		request.action = robottest.Action(request.body)
+		mu.Lock()
+		received = append(received, request)
+		mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(request.body))
+		robot.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &Provider{APIToken: "test-key", Endpoint: server.URL}, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedRequest(nil), received...)
+	}
+}
+
+// receivedActions returns the actions of requests.
+func receivedActions(requests []receivedRequest) []string {
+	var result []string
+	for _, request := range requests {
+		result = append(result, request.action)
+	}
+	return result
+}
+
+// writeRequests sends an ADDORUPDATERR and a DELRR request, after the GETZONE of each.
+func writeRequests(t *testing.T, p *Provider) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}}); err != nil {
+		t.Fatalf("AppendRecords() error = %v", err)
+	}
+	if _, err := p.DeleteRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "_acme-challenge", Type: "TXT", Data: "token"}}); err != nil {
+		t.Fatalf("DeleteRecords() error = %v", err)
+	}
+}
+
+func TestRequestContentLength(t *testing.T) {
+	p, received := newInspectingRobot(t, nil, fixture(t, "getzone"), fixture(t, "addorupdaterr"), fixture(t, "delrr"))
+	writeRequests(t, p)
+
+	requests := received()
+	if got, want := receivedActions(requests), []string{actionGetZone, actionAddOrUpdateRR, actionGetZone, actionDeleteRR}; !slices.Equal(got, want) {
+		t.Fatalf("robot received %v, want %v", got, want)
+	}
+	for _, request := range requests {
+		if len(request.transferEncoding) != 0 {
+			t.Errorf("%s request sent with Transfer-Encoding %v, want a plain body", request.action, request.transferEncoding)
+		}
+		if request.contentLength <= 0 || request.contentLength != int64(len(request.body)) {
+			t.Errorf("%s request Content-Length = %d, want the body size %d", request.action, request.contentLength, len(request.body))
+		}
+	}
+}
+
+func TestRequestContentType(t *testing.T) {
+	for _, test := range []struct {
+		contentType string
+		want        string
+	}{
+		{"", "application/xml"},
+		{"application/xml; charset=ISO-8859-1", "application/xml; charset=ISO-8859-1"},
+	} {
+		t.Run(test.want, func(t *testing.T) {
+			p, received := newInspectingRobot(t, nil, fixture(t, "getzone"), fixture(t, "addorupdaterr"), fixture(t, "delrr"))
+			p.ContentType = test.contentType
+			writeRequests(t, p)
+
+			for _, request := range received() {
+				if got := request.header.Values("Content-Type"); len(got) != 1 || got[0] != test.want {
+					t.Errorf("%s request Content-Type = %q, want %q", request.action, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+// largeDelete deletes enough records to exceed expectContinueMinBytes in a single DELRR request.
+func largeDelete(t *testing.T, p *Provider) error {
+	t.Helper()
+	var records []libdns.Record
+	for i := 0; i < 2000; i++ {
+		records = append(records, libdns.RR{Name: fmt.Sprintf("host%d", i), Type: "A", Data: "192.0.2.1"})
+	}
+	_, err := p.DeleteRecords(context.Background(), testZone, records)
+	return err
+}
+
+func TestExpectContinue(t *testing.T) {
+	rejectExpect := func(r *http.Request) int {
+		if r.Header.Get("Expect") != "" {
+			return http.StatusExpectationFailed
+		}
+		return 0
+	}
+	for _, test := range []struct {
+		name           string
+		expectContinue bool
+		reject         func(r *http.Request) int
+		wantExpect     []string // Expect header of each DELRR attempt
+	}{
+		{"off", false, nil, []string{""}},
+		{"accepted", true, nil, []string{"100-continue"}},
+		{"417 falls back to a plain request", true, rejectExpect, []string{"100-continue", ""}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, received := newInspectingRobot(t, test.reject, fixture(t, "getzone"), deletedExchange)
+			p.ExpectContinue = test.expectContinue
+
+			if err := largeDelete(t, p); err != nil {
+				t.Fatalf("DeleteRecords() error = %v", err)
+			}
+			requests := received()[1:] // after the GETZONE
+			var expect []string
+			for _, request := range requests {
+				expect = append(expect, request.header.Get("Expect"))
+			}
+			if !slices.Equal(expect, test.wantExpect) {
+				t.Fatalf("DELRR attempts carried Expect %q, want %q", expect, test.wantExpect)
+			}
+			last := requests[len(requests)-1]
+			if last.action != actionDeleteRR || int64(len(last.body)) != last.contentLength || last.contentLength < expectContinueMinBytes {
+				t.Errorf("robot received %s with %d of %d bytes, want the whole DELRR body", last.action, len(last.body), last.contentLength)
+			}
+		})
+	}
+}
+
+func TestExpectContinueSmallRequest(t *testing.T) {
+	p, received := newInspectingRobot(t, nil, fixture(t, "getzone"), fixture(t, "addorupdaterr"), fixture(t, "delrr"))
+	p.ExpectContinue = true
+	writeRequests(t, p)
+
+	for _, request := range received() {
+		if expect := request.header.Get("Expect"); expect != "" {
+			t.Errorf("%s request of %d bytes carried Expect %q, want none below %d bytes", request.action, request.contentLength, expect, expectContinueMinBytes)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"time"
+)
+
+// clock is the source of time of the provider: cache expiry, BIND serials and the waits between retries.
+// Tests replace it to control time without waiting.
+type clock interface {
+	Now() time.Time
+	// Sleep waits for d, or returns the context error as soon as ctx is done.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock is the wall clock, used unless the clock of the provider is replaced.
+type realClock struct{}
+
+// Now returns the current time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep waits for d on a timer, or returns the context error as soon as ctx is done.
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// now returns the current time of the clock of the provider.
+func (p *Provider) now() time.Time {
+	return p.timeSource().Now()
+}
+
+// sleep waits for d on the clock of the provider, or returns the context error as soon as ctx is done.
+func (p *Provider) sleep(ctx context.Context, d time.Duration) error {
+	return p.timeSource().Sleep(ctx, d)
+}
+
+// timeSource returns the clock of the provider, the wall clock unless replaced.
+func (p *Provider) timeSource() clock {
+	if p.clock != nil {
+		return p.clock
+	}
+	return realClock{}
+}
@@ -0,0 +1,132 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+)
+
+// fakeClock is a clock for tests. Time only moves on Advance and Sleep; Sleep returns at once and records
+// the requested durations.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+// newFakeClock returns a fake clock starting at a fixed time.
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the time of the clock.
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep advances the clock by d without waiting, unless ctx is done.
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.sleeps = append(c.sleeps, d)
+	return nil
+}
+
+// Advance moves the clock forward by d.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Sleeps returns the durations passed to Sleep so far.
+func (c *fakeClock) Sleeps() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.sleeps...)
+}
+
+// unavailable is a GETZONE exchange failing with HTTP 503.
+var unavailable = robottest.Exchange{Action: actionGetZone, StatusCode: http.StatusServiceUnavailable, Response: "Service Unavailable\n"}
+
+func TestZoneCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	p, server := newTestProvider(t, fixture(t, "getzone"))
+	p.ZoneCacheTTL = time.Minute
+	p.clock = clock
+
+	for _, step := range []struct {
+		advance time.Duration
+		fetches int
+	}{
+		{0, 1},
+		{59 * time.Second, 1},
+		{time.Second, 2}, // expired exactly at the TTL
+		{30 * time.Second, 2},
+	} {
+		clock.Advance(step.advance)
+		if _, err := p.GetRecords(ctx, testZone); err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+		if got := count(actions(server), actionGetZone); got != step.fetches {
+			t.Errorf("after advancing %v: %d GETZONE requests, want %d", step.advance, got, step.fetches)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	clock := newFakeClock()
+	p, server := newTestProvider(t, unavailable, unavailable, unavailable, fixture(t, "getzone"))
+	p.MaxRetries = 3
+	p.DisableRetryJitter = true
+	p.clock = clock
+
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if got := count(actions(server), actionGetZone); got != 4 {
+		t.Errorf("sent %d GETZONE requests, want 4", got)
+	}
+	want := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second}
+	if got := clock.Sleeps(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("waited %v between retries, want %v", got, want)
+	}
+}
+
+func TestRetryBackoffJitter(t *testing.T) {
+	clock := newFakeClock()
+	p, _ := newTestProvider(t, unavailable, unavailable, unavailable, fixture(t, "getzone"))
+	p.MaxRetries = 3
+	p.clock = clock
+
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	sleeps := clock.Sleeps()
+	if len(sleeps) != 3 {
+		t.Fatalf("waited %d times, want 3", len(sleeps))
+	}
+	for i, sleep := range sleeps {
+		if backoff := baseRetryDelay << i; sleep < 0 || sleep > backoff {
+			t.Errorf("retry %d waited %v, want at most %v", i+1, sleep, backoff)
+		}
+	}
+}
+
+func TestRetryDelayCapped(t *testing.T) {
+	p := &Provider{DisableRetryJitter: true}
+	if got := p.retryDelay(20); got != maxRetryDelay {
+		t.Errorf("retryDelay(20) = %v, want %v", got, maxRetryDelay)
+	}
+}
@@ -0,0 +1,80 @@
+package libdns_kyberio
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Validate checks the configuration of the provider without contacting the robot and returns all
+// problems found joined into one error, or nil. Services can call it at startup; Ping additionally
+// checks that the robot is reachable and accepts the key.
+func (p *Provider) Validate() error {
+	var errs []error
+
+	if p.APIToken == "" && p.KeyProvider == nil {
+		errs = append(errs, errors.New("no DDNS key: set APIToken or KeyProvider"))
+	}
+
+	for _, endpoint := range []struct {
+		name  string
+		value string
+	}{
+		{"Endpoint", p.Endpoint},
+		{"RootZoneEndpoint", p.RootZoneEndpoint},
+	} {
+		if endpoint.value == "" {
+			continue
+		}
+		u, err := url.Parse(endpoint.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", endpoint.name, err))
+		} else if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid %s %q: must be an http or https URL", endpoint.name, endpoint.value))
+		}
+	}
+
+	for _, duration := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"Timeout", p.Timeout},
+		{"DialTimeout", p.DialTimeout},
+		{"TLSHandshakeTimeout", p.TLSHandshakeTimeout},
+		{"ResponseHeaderTimeout", p.ResponseHeaderTimeout},
+		{"IdleConnTimeout", p.IdleConnTimeout},
+		{"ZoneCacheTTL", p.ZoneCacheTTL},
+		{"MinTTL", p.MinTTL},
+		{"DefaultTTL", p.DefaultTTL},
+	} {
+		if duration.value < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s %v: must not be negative", duration.name, duration.value))
+		}
+	}
+
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"MaxRequestBytes", p.MaxRequestBytes},
+		{"MaxResponseBytes", p.MaxResponseBytes},
+		{"MaxDeletes", p.MaxDeletes},
+		{"DeleteBatchSize", p.DeleteBatchSize},
+		{"MaxConcurrency", p.MaxConcurrency},
+		{"MaxRetries", p.MaxRetries},
+		{"RetryBudget", p.RetryBudget},
+	} {
+		if limit.value < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s %d: must not be negative", limit.name, limit.value))
+		}
+	}
+
+	switch p.ZoneMatch {
+	case "", ZoneMatchLongest, ZoneMatchShortest:
+	default:
+		errs = append(errs, fmt.Errorf("invalid ZoneMatch %q: must be %q or %q", p.ZoneMatch, ZoneMatchLongest, ZoneMatchShortest))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *Provider {
+		return &Provider{APIToken: "test-key", Endpoint: "https://robot.example/", RootZoneEndpoint: "http://robot.example:8080/"}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Validate() of a valid configuration error = %v", err)
+	}
+	keyProvider := valid()
+	keyProvider.APIToken = ""
+	keyProvider.KeyProvider = func(ctx context.Context) (string, error) { return "test-key", nil }
+	if err := keyProvider.Validate(); err != nil {
+		t.Errorf("Validate() with a KeyProvider error = %v", err)
+	}
+
+	for _, test := range []struct {
+		field  string
+		modify func(p *Provider)
+	}{
+		{"DDNS key", func(p *Provider) { p.APIToken = "" }},
+		{"Endpoint", func(p *Provider) { p.Endpoint = "ftp://robot.example/" }},
+		{"Endpoint", func(p *Provider) { p.Endpoint = "https://" }},
+		{"Endpoint", func(p *Provider) { p.Endpoint = "http://robot.example/%zz" }},
+		{"RootZoneEndpoint", func(p *Provider) { p.RootZoneEndpoint = "robot.example" }},
+		{"Timeout", func(p *Provider) { p.Timeout = -time.Second }},
+		{"DialTimeout", func(p *Provider) { p.DialTimeout = -time.Second }},
+		{"TLSHandshakeTimeout", func(p *Provider) { p.TLSHandshakeTimeout = -time.Second }},
+		{"ResponseHeaderTimeout", func(p *Provider) { p.ResponseHeaderTimeout = -time.Second }},
+		{"IdleConnTimeout", func(p *Provider) { p.IdleConnTimeout = -time.Second }},
+		{"ZoneCacheTTL", func(p *Provider) { p.ZoneCacheTTL = -time.Second }},
+		{"MinTTL", func(p *Provider) { p.MinTTL = -time.Second }},
+		{"DefaultTTL", func(p *Provider) { p.DefaultTTL = -time.Second }},
+		{"MaxRequestBytes", func(p *Provider) { p.MaxRequestBytes = -1 }},
+		{"MaxResponseBytes", func(p *Provider) { p.MaxResponseBytes = -1 }},
+		{"MaxDeletes", func(p *Provider) { p.MaxDeletes = -1 }},
+		{"DeleteBatchSize", func(p *Provider) { p.DeleteBatchSize = -1 }},
+		{"MaxConcurrency", func(p *Provider) { p.MaxConcurrency = -1 }},
+		{"MaxRetries", func(p *Provider) { p.MaxRetries = -1 }},
+		{"RetryBudget", func(p *Provider) { p.RetryBudget = -1 }},
+		{"ZoneMatch", func(p *Provider) { p.ZoneMatch = "first" }},
+	} {
+		t.Run(test.field, func(t *testing.T) {
+			p := valid()
+			test.modify(p)
+			err := p.Validate()
+			if err == nil || !strings.Contains(err.Error(), test.field) {
+				t.Errorf("Validate() error = %v, want one naming %s", err, test.field)
+			}
+		})
+	}
+}
+
+func TestValidateJoinsProblems(t *testing.T) {
+	p := &Provider{Timeout: -time.Second, ZoneMatch: "first"}
+
+	err := p.Validate()
+	if err == nil {
+		t.Fatal("Validate() error = nil, want the problems of all fields")
+	}
+	for _, field := range []string{"DDNS key", "Timeout", "ZoneMatch"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Validate() error = %v, want it to name %s", err, field)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+)
+
+// requestIDHeader carries the correlation ID of a request to the robot.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key of the correlation ID set by WithRequestID.
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx that makes requests to the robot carry id in the X-Request-ID
+// header. Without it, every request gets a random ID. The ID is echoed in any returned *APIError.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestID returns the correlation ID stored in ctx, or a new random ID.
+func requestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ddnsKeyKey is the context key of the DDNS key set by WithDDNSKey.
+type ddnsKeyKey struct{}
+
+// WithDDNSKey returns a copy of ctx that makes the provider methods authenticate with key instead of
+// the APIToken of the provider. This lets one provider serve several tenants.
+func WithDDNSKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, ddnsKeyKey{}, key)
+}
+
+// ddnsKey returns the DDNS key to use for ctx: the key set by WithDDNSKey, the key supplied by KeyProvider,
+// or the APIToken of the provider.
+func (p *Provider) ddnsKey(ctx context.Context) (string, error) {
+	if key, ok := ctx.Value(ddnsKeyKey{}).(string); ok && key != "" {
+		return key, nil
+	}
+	if p.KeyProvider == nil {
+		return p.APIToken, nil
+	}
+
+	p.keyMu.Lock()
+	defer p.keyMu.Unlock()
+	if p.cachedKey != "" {
+		return p.cachedKey, nil
+	}
+	key, err := p.KeyProvider(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error obtaining DDNS key: %w", err)
+	}
+	p.cachedKey = key
+	return key, nil
+}
+
+// refreshKey drops the cached key if it is still stale, the key the robot rejected, and obtains a new one
+// from KeyProvider. The zones cached for the stale key are dropped along with it.
+func (p *Provider) refreshKey(ctx context.Context, stale string) (string, error) {
+	p.keyMu.Lock()
+	rotated := p.cachedKey == stale
+	if rotated {
+		p.cachedKey = ""
+	}
+	p.keyMu.Unlock()
+	if rotated {
+		p.invalidateKey(stale)
+	}
+	return p.ddnsKey(ctx)
+}
+
+// rotatedOut reports whether ddnsKey was supplied by KeyProvider and has been replaced by a fresh key
+// since, e.g. while a request sent with it was retried.
+func (p *Provider) rotatedOut(ctx context.Context, ddnsKey string) bool {
+	if p.KeyProvider == nil {
+		return false
+	}
+	if key, ok := ctx.Value(ddnsKeyKey{}).(string); ok && key != "" {
+		return false
+	}
+	p.keyMu.Lock()
+	defer p.keyMu.Unlock()
+	return p.cachedKey != "" && p.cachedKey != ddnsKey
+}
+
+// rotateKey returns a copy of a request that was rejected for its DDNS key, carrying a fresh key from
+// KeyProvider instead. It reports false if the key did not come from KeyProvider or did not change.
+func (p *Provider) rotateKey(request *http.Request) (*http.Request, bool) {
+	ctx := request.Context()
+	if p.KeyProvider == nil || request.GetBody == nil {
+		return nil, false
+	}
+	if key, ok := ctx.Value(ddnsKeyKey{}).(string); ok && key != "" {
+		return nil, false
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, false
+	}
+	match := ddnsKeyAttr.FindSubmatch(data)
+	if match == nil {
+		return nil, false
+	}
+	stale := html.UnescapeString(string(match[1]))
+
+	key, err := p.refreshKey(ctx, stale)
+	if err != nil || key == stale {
+		return nil, false
+	}
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(key))
+	data = ddnsKeyAttr.ReplaceAllLiteral(data, []byte(`ddnskey="`+escaped.String()+`"`))
+
+	rotated, err := http.NewRequestWithContext(ctx, request.Method, request.URL.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	rotated.Header = request.Header.Clone()
+	p.debug(ctx, "retrying robot request with a new DDNS key")
+	return rotated, true
+}
+
+// ddnsKeyAttr matches the DDNS key attribute of a request.
+var ddnsKeyAttr = regexp.MustCompile(`ddnskey="([^"]*)"`)
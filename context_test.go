@@ -0,0 +1,118 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+)
+
+// rotatingKeys returns a KeyProvider handing out k1, k2, ... one per call, and the number of calls so far.
+func rotatingKeys() (func(ctx context.Context) (string, error), *int) {
+	calls := 0
+	return func(ctx context.Context) (string, error) {
+		calls++
+		return fmt.Sprintf("k%d", calls), nil
+	}, &calls
+}
+
+// sentKeys returns the DDNS keys of the requests for action the server received, in order.
+func sentKeys(t *testing.T, server *robottest.Server, action string) []string {
+	t.Helper()
+	var keys []string
+	for _, zone := range sentRequests(t, server, action) {
+		keys = append(keys, zone.DDNSKey)
+	}
+	return keys
+}
+
+func TestKeyProviderRotation(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone-denied"), fixture(t, "getzone"))
+	p.APIToken = ""
+	keys, calls := rotatingKeys()
+	p.KeyProvider = keys
+
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("second GetRecords() error = %v", err)
+	}
+
+	if got, want := sentKeys(t, server, actionGetZone), []string{"k1", "k2", "k2"}; !slices.Equal(got, want) {
+		t.Errorf("sent GETZONE with keys %v, want %v: the denied k1 retried once with k2, which is kept", got, want)
+	}
+	if *calls != 2 {
+		t.Errorf("KeyProvider called %d times, want 2", *calls)
+	}
+}
+
+func TestKeyProviderRotationFailsOnce(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone-denied"))
+	p.APIToken = ""
+	keys, _ := rotatingKeys()
+	p.KeyProvider = keys
+
+	if _, err := p.GetRecords(context.Background(), testZone); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("GetRecords() error = %v, want %v", err, ErrAuthFailed)
+	}
+	if got, want := sentKeys(t, server, actionGetZone), []string{"k1", "k2"}; !slices.Equal(got, want) {
+		t.Errorf("sent GETZONE with keys %v, want %v without further retries", got, want)
+	}
+}
+
+func TestKeyRotationDropsCachedZones(t *testing.T) {
+	denied := robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="denied"></zoneRequest>`}
+	p, server := newTestProvider(t, fixture(t, "getzone"), denied, robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="ok"></zoneRequest>`})
+	p.APIToken = ""
+	p.ZoneCacheTTL = time.Minute
+	keys, _ := rotatingKeys()
+	p.KeyProvider = keys
+
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	// the probe is denied for k1 and retried with k2, which rotates k1 out
+	if ok, err := p.CanWrite(context.Background(), "example.org."); err != nil || !ok {
+		t.Fatalf("CanWrite() = %v, %v, want true", ok, err)
+	}
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("GetRecords() after the rotation error = %v", err)
+	}
+
+	if got, want := sentKeys(t, server, actionGetZone), []string{"k1", "k2"}; !slices.Equal(got, want) {
+		t.Errorf("sent GETZONE with keys %v, want %v: the zone cached for k1 must not survive the rotation", got, want)
+	}
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	for key := range p.zoneCache {
+		if key.ddnsKey == "k1" {
+			t.Errorf("zone %s is still cached for the rotated-out key k1", key.zoneName)
+		}
+	}
+}
+
+func TestKeyRotationDuringFetchIsNotCached(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone-denied"), fixture(t, "getzone"))
+	p.APIToken = ""
+	p.ZoneCacheTTL = time.Minute
+	keys, _ := rotatingKeys()
+	p.KeyProvider = keys
+
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	p.cacheMu.Lock()
+	_, cached := p.zoneCache[zoneCacheKey{ddnsKey: "k1", zoneName: testZone}]
+	p.cacheMu.Unlock()
+	if cached {
+		t.Error("the zone fetched with k2 is cached for the rotated-out key k1")
+	}
+	if got := len(sentKeys(t, server, actionGetZone)); got != 2 {
+		t.Errorf("sent %d GETZONE requests, want 2", got)
+	}
+}
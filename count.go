@@ -0,0 +1,90 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// countRecords returns the number of records of the zone. A cached zone is counted directly; otherwise the
+// GETZONE response is scanned for <rr> elements without decoding them, unless the robot reports a count.
+// The status of the response is checked before, so a rejected key fails instead of counting no records.
+func (p *Provider) countRecords(ctx context.Context, ddnsKey string, zoneName string) (int, error) {
+	if p.ZoneCacheTTL > 0 {
+		p.cacheMu.Lock()
+		entry, ok := p.zoneCache[zoneCacheKey{ddnsKey: ddnsKey, zoneName: zoneName}]
+		p.cacheMu.Unlock()
+		if ok && p.now().Before(entry.expires) {
+			return len(entry.export.records), nil
+		}
+	}
+
+	body, err := p.fetchZone(ctx, ddnsKey, zoneName, "")
+	if err != nil {
+		return 0, err
+	}
+	count, err := countElements(body)
+	if err != nil {
+		return 0, fmt.Errorf("error unmarshaling XML response: %v", err)
+	}
+	return count, nil
+}
+
+// countView counts the records of the caller zone of view. If it lies inside the robot zone, only the
+// records below it are counted, which needs the records themselves.
+func (p *Provider) countView(ctx context.Context, ddnsKey string, view zoneView) (int, error) {
+	if view.prefix == "" {
+		return p.countRecords(ctx, ddnsKey, view.zone)
+	}
+	zoneExport, err := p.zone(ctx, ddnsKey, view.zone)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, record := range zoneExport.records {
+		if _, ok := view.fromRobotName(record.Host); ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// countElements counts the <rr> elements of a zone response. If the root or zone element carries a count
+// attribute, its value is returned instead.
+func countElements(body []byte) (int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(trimXMLPrefix(body)))
+	decoder.CharsetReader = charsetReader
+
+	count := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "rr":
+			count++
+			if err := decoder.Skip(); err != nil {
+				return 0, err
+			}
+		case "zone", "zoneRequest":
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "count" {
+					if n, err := strconv.Atoi(attr.Value); err == nil {
+						return n, nil
+					}
+				}
+			}
+		}
+	}
+}
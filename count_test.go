@@ -0,0 +1,35 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+)
+
+func TestCountRecords(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		exchange robottest.Exchange
+		want     int
+		wantErr  error
+	}{
+		{"export", fixture(t, "getzone"), 5, nil},
+		{"reported count", robottest.Exchange{Action: actionGetZone, Response: `<zoneRequest status="ok"><zone name="example.com" count="42"></zone></zoneRequest>`}, 42, nil},
+		{"denied", fixture(t, "getzone-denied"), 0, ErrAuthFailed},
+		{"missing status", robottest.Exchange{Action: actionGetZone, Response: `<zoneRequest><rr host="www" type="A" value="192.0.2.1"></rr></zoneRequest>`}, 0, ErrMissingStatus},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, _ := newTestProvider(t, test.exchange)
+
+			count, err := p.CountRecords(context.Background(), testZone)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("CountRecords() error = %v, want %v", err, test.wantErr)
+			}
+			if count != test.want {
+				t.Errorf("CountRecords() = %d, want %d", count, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/libdns/libdns"
+)
+
+// updateDynamicIP points the A and/or AAAA records of name to the given addresses. The record type is
+// derived from each address; IPv4-mapped IPv6 addresses count as IPv4. Only the types present in ips are
+// changed, any other address of those types is removed.
+func (p *Provider) updateDynamicIP(ctx context.Context, ddnsKey string, zoneName string, name string, ips []string) ([]libdns.Record, error) {
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%w: no address given for %q", ErrInvalidRecord, name)
+	}
+
+	var records []libdns.Record
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an IP address", ErrInvalidRecord, ip)
+		}
+		addr = addr.Unmap()
+
+		rtype := "AAAA"
+		if addr.Is4() {
+			rtype = "A"
+		}
+		records = append(records, libdns.RR{Name: name, Type: rtype, Data: addr.String()})
+	}
+
+	return p.setRecords(ctx, ddnsKey, zoneName, records)
+}
@@ -0,0 +1,135 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// zoneEnvelope is the decoded response of a zone action. The robot places records and SOA either directly
+// below the root element (ADDORUPDATERR, DELRR) or inside a nested <zone> element (GETZONE), and uses
+// <zoneRequest> or <zone> as the root. Both shapes decode into the same envelope.
+type zoneEnvelope struct {
+	XMLName  xml.Name
+	Status   string           `xml:"status,attr"`
+	Name     string           `xml:"name,attr"`
+	ZoneAttr string           `xml:"zone,attr"`
+	Action   string           `xml:"action,attr"`
+	Reseller string           `xml:"reseller,attr"`
+	DNSSec   bool             `xml:"dnssec,attr"`
+	SOA      *SOA             `xml:"soa"`
+	Records  []ResourceRecord `xml:"rr"`
+	Warnings []string         `xml:"warning"`
+	Zone     *zoneEnvelope    `xml:"zone"`
+}
+
+// decodeZoneResponse decodes the response of a zone action, accepting both envelope shapes of the robot.
+// Attributes, SOA and records of a nested <zone> element are merged into the returned envelope.
+func decodeZoneResponse(body []byte) (zoneEnvelope, error) {
+	var envelope zoneEnvelope
+	if err := unmarshalXML(body, &envelope); err != nil {
+		return zoneEnvelope{}, err
+	}
+
+	if nested := envelope.Zone; nested != nil {
+		envelope.Records = append(envelope.Records, nested.Records...)
+		envelope.Warnings = append(envelope.Warnings, nested.Warnings...)
+		if envelope.SOA == nil {
+			envelope.SOA = nested.SOA
+		}
+		if envelope.Status == "" {
+			envelope.Status = nested.Status
+		}
+		if envelope.Name == "" {
+			envelope.Name = nested.Name
+		}
+		if envelope.Reseller == "" {
+			envelope.Reseller = nested.Reseller
+		}
+		envelope.DNSSec = envelope.DNSSec || nested.DNSSec
+		envelope.Zone = nil
+	}
+	if envelope.Name == "" {
+		envelope.Name = envelope.ZoneAttr
+	}
+
+	return envelope, nil
+}
+
+// responseStatus returns the status of a zone response without decoding it as a whole: the status attribute
+// of the root element, or of a nested <zone> element if the root has none, like decodeZoneResponse.
+func responseStatus(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(trimXMLPrefix(body)))
+	decoder.CharsetReader = charsetReader
+
+	rootSeen := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF && rootSeen {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !rootSeen {
+			rootSeen = true
+			if status := attrValue(start, "status"); status != "" {
+				return status, nil
+			}
+			continue
+		}
+		if start.Name.Local == "zone" {
+			return attrValue(start, "status"), nil
+		}
+		// other children of the root, e.g. records, do not hold the status
+		if err := decoder.Skip(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// attrValue returns the value of the attribute of element with the given local name, or "" if it has none.
+func attrValue(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// soa returns the SOA of the envelope, or the zero SOA if the response has none.
+func (e zoneEnvelope) soa() SOA {
+	if e.SOA == nil {
+		return SOA{}
+	}
+	return *e.SOA
+}
+
+// utf8BOM is the byte order mark some servers put in front of a UTF-8 document.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// trimXMLPrefix strips a leading byte order mark and whitespace from a response body, since they are not
+// allowed in front of the XML declaration.
+func trimXMLPrefix(body []byte) []byte {
+	return bytes.TrimLeft(bytes.TrimPrefix(bytes.TrimLeft(body, " \t\r\n"), utf8BOM), " \t\r\n")
+}
+
+// logWarnings logs the warnings the robot reported along with a successful action, for the response and for
+// single records, e.g. a record shadowed by a wildcard. They do not fail the action.
+func (p *Provider) logWarnings(ctx context.Context, action string, zoneName string, response zoneEnvelope) {
+	for _, warning := range response.Warnings {
+		p.warn(ctx, "robot reported a warning", "action", action, "zone", zoneName, "warning", strings.TrimSpace(warning))
+	}
+	for _, record := range response.Records {
+		if record.Warning != "" {
+			p.warn(ctx, "robot reported a warning for a record", "action", action, "zone", zoneName, "host", record.Host, "type", record.Type, "warning", record.Warning)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestDecodeByteOrderMark(t *testing.T) {
+	body := []byte(fixture(t, "getzone-bom").Response)
+	if !bytes.HasPrefix(body, utf8BOM) {
+		t.Fatal("fixture getzone-bom does not start with a byte order mark")
+	}
+
+	status, err := responseStatus(body)
+	if err != nil || status != "ok" {
+		t.Errorf("responseStatus() = %q, %v, want ok", status, err)
+	}
+	response, err := decodeZoneResponse(body)
+	if err != nil {
+		t.Fatalf("decodeZoneResponse() error = %v", err)
+	}
+	if len(response.Records) != 2 || response.Records[1].Value != "grüße" {
+		t.Errorf("decodeZoneResponse() records = %+v, want www and the UTF-8 TXT value grüße", response.Records)
+	}
+
+	p, _ := newTestProvider(t, fixture(t, "getzone-bom"))
+	records, err := p.GetRecords(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("GetRecords() = %v, want 2 records", records)
+	}
+}
+
+func TestWriteWarnings(t *testing.T) {
+	var logged bytes.Buffer
+	p, _ := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+		Action: actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok"><warning>zone is locked for review</warning>` +
+			`<rr host="new" type="A" value="192.0.2.7" performedAction="added" warning="shadowed by a wildcard"></rr></zoneRequest>`,
+	})
+	p.Logger = slog.New(slog.NewTextHandler(&logged, nil))
+
+	results, err := p.AppendRecordsWithResults(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "new", Type: "A", Data: "192.0.2.7"}})
+	if err != nil {
+		t.Fatalf("AppendRecordsWithResults() error = %v, want the warnings not to fail the write", err)
+	}
+	if len(results) != 1 || results[0].Action != ActionAdded || results[0].Warning != "shadowed by a wildcard" {
+		t.Errorf("AppendRecordsWithResults() = %+v, want the record added with its warning", results)
+	}
+	for _, warning := range []string{"zone is locked for review", "shadowed by a wildcard"} {
+		if !strings.Contains(logged.String(), warning) {
+			t.Errorf("logged %q, want the warning %q", logged.String(), warning)
+		}
+	}
+}
+
+func TestDeleteWarnings(t *testing.T) {
+	var logged bytes.Buffer
+	p, _ := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+		Action:   actionDeleteRR,
+		Response: `<zoneRequest status="ok"><rr host="_acme-challenge" type="TXT" value="token" performedAction="deleted" warning="delegation left without glue"></rr></zoneRequest>`,
+	})
+	p.Logger = slog.New(slog.NewTextHandler(&logged, nil))
+
+	results, err := p.DeleteRecordsWithResults(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "_acme-challenge", Type: "TXT", Data: "token"}})
+	if err != nil {
+		t.Fatalf("DeleteRecordsWithResults() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Action != ActionDeleted || results[0].Warning != "delegation left without glue" {
+		t.Errorf("DeleteRecordsWithResults() = %+v, want the record deleted with its warning", results)
+	}
+	if !strings.Contains(logged.String(), "delegation left without glue") {
+		t.Errorf("logged %q, want the warning", logged.String())
+	}
+}
+
+func TestReadTXTCharacterStrings(t *testing.T) {
+	for _, test := range []struct {
+		value, want string
+	}{
+		{`"v=spf1 " "-all"`, "v=spf1 -all"},
+		{`"part one"  "part two"`, "part onepart two"},
+		{`"single"`, "single"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"a\059b"`, "a;b"},
+		{`v=spf1 -all`, "v=spf1 -all"},
+		{`"unterminated`, `"unterminated`},
+		{`"quoted" tail`, `"quoted" tail`},
+	} {
+		t.Run(test.value, func(t *testing.T) {
+			p, _ := newTestProvider(t, zoneExchange(`<rr host="@" type="TXT" value='`+test.value+`'></rr>`))
+			p.ParseRecords = true
+
+			records, err := p.GetRecords(context.Background(), testZone)
+			if err != nil {
+				t.Fatalf("GetRecords() error = %v", err)
+			}
+			if len(records) != 1 {
+				t.Fatalf("GetRecords() = %v, want one record", records)
+			}
+			txt, ok := records[0].(libdns.TXT)
+			if !ok || txt.Text != test.want {
+				t.Errorf("GetRecords() = %#v, want libdns.TXT with text %q", records[0], test.want)
+			}
+		})
+	}
+}
+
+func TestReadUnknownRecordTypes(t *testing.T) {
+	p, _ := newTestProvider(t, zoneExchange(`<rr host="www" type="A" value="192.0.2.1"></rr>`+
+		`<rr host="@" type="SPF" value="v=spf1 -all"></rr>`+
+		`<rr host="@" type="TYPE65534" value="\# 4 0A000001"></rr>`+
+		`<rr host="bad" type="MX" value="not an mx"></rr>`))
+
+	for _, parse := range []bool{false, true} {
+		p.ParseRecords = parse
+		records, err := p.GetRecords(context.Background(), testZone)
+		if err != nil {
+			t.Fatalf("GetRecords() with ParseRecords %t error = %v", parse, err)
+		}
+		if len(records) != 4 {
+			t.Fatalf("GetRecords() with ParseRecords %t = %v, want all 4 records", parse, records)
+		}
+		for _, record := range records[1:] {
+			if _, ok := record.(libdns.RR); !ok {
+				t.Errorf("GetRecords() with ParseRecords %t returned %#v, want a plain libdns.RR", parse, record)
+			}
+		}
+		if got := records[2].RR().Type; got != "TYPE65534" {
+			t.Errorf("GetRecords() type = %q, want TYPE65534", got)
+		}
+		if _, ok := records[0].(libdns.Address); ok != parse {
+			t.Errorf("GetRecords() with ParseRecords %t returned %#v for the A record", parse, records[0])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// zoneDiff is the changeset that makes the zone hold the desired records, with the semantics of SetRecords.
+type zoneDiff struct {
+	toAdd    []libdns.Record // Records to add
+	toUpdate []libdns.Record // New values of single-valued RRsets that replace the current value in place, and new TTLs of current values
+	toDelete []libdns.Record // Current records of the desired RRsets that are not desired
+}
+
+// diff fetches the zone and computes the changeset between the desired and the current records without
+// applying it. It shares planSet with SetRecords, so records are deduplicated, validated, clamped to MinTTL
+// and compared by RRset, value and TTL the same way. Records whose value is current but not their TTL are
+// updates. RRsets without desired records are left alone, as SetRecords does.
+func (p *Provider) diff(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (zoneDiff, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return zoneDiff{}, err
+	}
+	ttl := time.Duration(zoneExport.ttl) * time.Second
+
+	_, plans, err := p.planSet(ctx, zoneExport, zoneName, records)
+	if err != nil {
+		return zoneDiff{}, err
+	}
+
+	var changes zoneDiff
+	for _, plan := range plans {
+		for _, record := range plan.retimed {
+			changes.toUpdate = append(changes.toUpdate, toLibdnsRR(record, ttl))
+		}
+		desired := len(plan.unchanged) + len(plan.retimed) + len(plan.missing)
+		if desired == 1 && len(plan.missing) == 1 && len(plan.extra) == 1 {
+			changes.toUpdate = append(changes.toUpdate, toLibdnsRR(plan.missing[0], ttl))
+			continue
+		}
+		for _, record := range plan.missing {
+			changes.toAdd = append(changes.toAdd, toLibdnsRR(record, ttl))
+		}
+		for _, record := range plan.extra {
+			changes.toDelete = append(changes.toDelete, toLibdnsRR(record, ttl))
+		}
+	}
+	return changes, nil
+}
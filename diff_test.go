@@ -0,0 +1,63 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestDiffMatchesSetRecords(t *testing.T) {
+	for _, test := range []struct {
+		name                   string
+		desired                []libdns.Record
+		minTTL                 time.Duration
+		add, update, remove    int
+		writes, deletesWritten int
+	}{
+		{"unchanged", aRecords("192.0.2.1"), 0, 0, 0, 0, 0, 0},
+		{"only the TTL differs", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: 600 * time.Second}}, 0, 0, 1, 0, 1, 0},
+		{"zone TTL requested", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: time.Hour}}, 0, 0, 0, 0, 0, 0},
+		{"TTL raised to MinTTL", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: 60 * time.Second}}, 2 * time.Hour, 0, 1, 0, 1, 0},
+		{"duplicates", aRecords("192.0.2.1", "192.0.2.1"), 0, 0, 0, 0, 0, 0},
+		{"value replaced", aRecords("192.0.2.2"), 0, 0, 1, 0, 1, 1},
+		{"value added", aRecords("192.0.2.1", "192.0.2.2"), 0, 1, 0, 0, 1, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"), fixture(t, "delrr"))
+			p.MinTTL = test.minTTL
+
+			toAdd, toUpdate, toDelete, err := p.Diff(context.Background(), testZone, test.desired)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if len(toAdd) != test.add || len(toUpdate) != test.update || len(toDelete) != test.remove {
+				t.Errorf("Diff() = %v, %v, %v, want %d additions, %d updates, %d deletions", toAdd, toUpdate, toDelete, test.add, test.update, test.remove)
+			}
+
+			// SetRecords sends exactly what Diff announced; its result is not of interest here
+			p.SetRecords(context.Background(), testZone, test.desired)
+			if got := len(sentRequests(t, server, actionAddOrUpdateRR)); got != test.writes {
+				t.Errorf("SetRecords() sent %d ADDORUPDATERR requests, want %d", got, test.writes)
+			}
+			if got := len(sentRequests(t, server, actionDeleteRR)); got != test.deletesWritten {
+				t.Errorf("SetRecords() sent %d DELRR requests, want %d", got, test.deletesWritten)
+			}
+		})
+	}
+}
+
+func TestDiffValidatesLikeSetRecords(t *testing.T) {
+	p, _ := newTestProvider(t, fixture(t, "getzone"))
+
+	desired := []libdns.Record{
+		libdns.RR{Name: "alias", Type: "CNAME", Data: "a.example.net."},
+		libdns.RR{Name: "alias", Type: "CNAME", Data: "b.example.net."},
+	}
+	_, _, _, diffErr := p.Diff(context.Background(), testZone, desired)
+	_, setErr := p.SetRecords(context.Background(), testZone, desired)
+	if diffErr == nil || setErr == nil {
+		t.Errorf("Diff() error = %v, SetRecords() error = %v, want both to reject the set", diffErr, setErr)
+	}
+}
@@ -0,0 +1,146 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// DNSKEY flags, RFC 4034 section 2.1.1.
+const (
+	dnskeyFlagZone = 0x0100
+	dnskeyFlagSEP  = 0x0001
+)
+
+// digestSHA256 is the DS digest type for SHA-256, RFC 4509.
+const digestSHA256 = 2
+
+// getDS returns the DS records to publish in the parent zone. The robot has no action for key material;
+// the records are derived from the DNSKEY records at the apex of the zone export, preferring keys with
+// the SEP flag set and using SHA-256 digests. If DNSSEC is not active or the robot does not export the
+// DNSKEY records, an error wrapping ErrNoKeyMaterial is returned.
+func (p *Provider) getDS(ctx context.Context, ddnsKey string, zoneName string) ([]libdns.RR, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return nil, err
+	}
+	if !zoneExport.dnssec {
+		return nil, fmt.Errorf("%w: DNSSEC is not active for zone %s", ErrNoKeyMaterial, zoneName)
+	}
+
+	var keys, sepKeys []ResourceRecord
+	for _, record := range zoneExport.records {
+		if record.Host != "@" || !strings.EqualFold(record.Type, "DNSKEY") {
+			continue
+		}
+		flags, _, _, _, err := parseDNSKEY(record.Value)
+		if err != nil || flags&dnskeyFlagZone == 0 {
+			continue
+		}
+		keys = append(keys, record)
+		if flags&dnskeyFlagSEP != 0 {
+			sepKeys = append(sepKeys, record)
+		}
+	}
+	if len(sepKeys) > 0 {
+		keys = sepKeys
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: the robot does not export DNSKEY records for zone %s", ErrNoKeyMaterial, zoneName)
+	}
+
+	ttl := time.Duration(zoneExport.ttl) * time.Second
+	result := make([]libdns.RR, 0, len(keys))
+	for _, key := range keys {
+		data, err := dsData(zoneName, key.Value)
+		if err != nil {
+			return nil, err
+		}
+		rr := toLibdnsRR(key, ttl)
+		rr.Type = "DS"
+		rr.Data = data
+		result = append(result, rr)
+	}
+	return result, nil
+}
+
+// dsData returns the RDATA of the SHA-256 DS record for a DNSKEY of the zone, RFC 4034 section 5.1.4.
+func dsData(zoneName string, dnskey string) (string, error) {
+	flags, protocol, algorithm, publicKey, err := parseDNSKEY(dnskey)
+	if err != nil {
+		return "", err
+	}
+
+	rdata := make([]byte, 4, 4+len(publicKey))
+	binary.BigEndian.PutUint16(rdata, flags)
+	rdata[2] = protocol
+	rdata[3] = algorithm
+	rdata = append(rdata, publicKey...)
+
+	digest := sha256.New()
+	digest.Write(wireName(zoneName))
+	digest.Write(rdata)
+
+	return fmt.Sprintf("%d %d %d %s", keyTag(rdata), algorithm, digestSHA256, strings.ToUpper(hex.EncodeToString(digest.Sum(nil)))), nil
+}
+
+// parseDNSKEY parses the presentation form of DNSKEY RDATA: <flags> <protocol> <algorithm> <public key>.
+func parseDNSKEY(value string) (flags uint16, protocol uint8, algorithm uint8, publicKey []byte, err error) {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return 0, 0, 0, nil, fmt.Errorf("%w: malformed DNSKEY value %q", ErrInvalidRecord, value)
+	}
+	f, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("%w: malformed DNSKEY flags %q", ErrInvalidRecord, fields[0])
+	}
+	proto, err := strconv.ParseUint(fields[1], 10, 8)
+	if err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("%w: malformed DNSKEY protocol %q", ErrInvalidRecord, fields[1])
+	}
+	alg, err := strconv.ParseUint(fields[2], 10, 8)
+	if err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("%w: malformed DNSKEY algorithm %q", ErrInvalidRecord, fields[2])
+	}
+	publicKey, err = base64.StdEncoding.DecodeString(strings.Join(fields[3:], ""))
+	if err != nil {
+		return 0, 0, 0, nil, fmt.Errorf("%w: malformed DNSKEY public key: %v", ErrInvalidRecord, err)
+	}
+	return uint16(f), uint8(proto), uint8(alg), publicKey, nil
+}
+
+// keyTag computes the key tag of DNSKEY RDATA, RFC 4034 appendix B.
+func keyTag(rdata []byte) uint16 {
+	var sum uint32
+	for i, b := range rdata {
+		if i&1 == 0 {
+			sum += uint32(b) << 8
+		} else {
+			sum += uint32(b)
+		}
+	}
+	sum += sum >> 16 & 0xffff
+	return uint16(sum)
+}
+
+// wireName returns the canonical wire format of a domain name: lowercase labels, each prefixed with its
+// length, terminated by the root label.
+func wireName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.ToLower(strings.TrimSuffix(name, ".")), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
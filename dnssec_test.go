@@ -0,0 +1,72 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestGetDS(t *testing.T) {
+	p, _ := newTestProvider(t, fixture(t, "getzone-dnssec"))
+
+	records, err := p.GetDS(context.Background(), "dskey.example.com.")
+	if err != nil {
+		t.Fatalf("GetDS() error = %v", err)
+	}
+	// the DS record of RFC 4509 section 2.3 for the same key
+	const want = "60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"
+	if len(records) != 1 || records[0].Type != "DS" || records[0].Data != want {
+		t.Errorf("GetDS() = %v, want DS %s", records, want)
+	}
+}
+
+func TestGetDSWithoutDNSSEC(t *testing.T) {
+	p, _ := newTestProvider(t, fixture(t, "getzone"))
+
+	if _, err := p.GetDS(context.Background(), testZone); !errors.Is(err, ErrNoKeyMaterial) {
+		t.Errorf("GetDS() error = %v, want %v", err, ErrNoKeyMaterial)
+	}
+}
+
+func TestDSRoundTrip(t *testing.T) {
+	const (
+		written = "60485 5 2 d4b7d520e7bb5f0f67674a0cceb1e3e0 614b93c4f9e99b8383f6a1e4469da50a"
+		want    = "60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A"
+	)
+	ctx := context.Background()
+	p, server := newTestProvider(t,
+		zoneExchange(""),
+		robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="ok"><rr host="sub" type="DS" value="` + want + `" performedAction="added"></rr></zoneRequest>`},
+		// the robot may export the digest in lowercase and split into blocks
+		zoneExchange(`<rr host="sub" type="DS" value="`+written+`"></rr>`),
+	)
+
+	if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "sub", Type: "DS", Data: written}}); err != nil {
+		t.Fatalf("AppendRecords() error = %v", err)
+	}
+	if writes := sentRequests(t, server, actionAddOrUpdateRR); len(writes) != 1 || writes[0].Records[0].Value != want {
+		t.Errorf("sent ADDORUPDATERR %+v, want the DS value %s", writes, want)
+	}
+
+	records, err := p.GetRecords(ctx, testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].RR().Type != "DS" {
+		t.Fatalf("GetRecords() = %v, want the DS record", records)
+	}
+	if got := records[0].RR().Data; got != want {
+		t.Errorf("GetRecords() DS = %q, want key tag, algorithm, digest type and digest of %q", got, want)
+	}
+
+	// the record read back is the record written
+	if _, err := p.SetRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "sub", Type: "DS", Data: want}}); err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if got := count(actions(server), actionAddOrUpdateRR); got != 1 {
+		t.Errorf("sent %d ADDORUPDATERR requests, want no second one for the DS record the zone holds", got)
+	}
+}
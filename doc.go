@@ -0,0 +1,25 @@
+// Package libdns_kyberio implements the libdns interfaces for Kyberio, managing DNS records through the
+// s-dns robot.
+//
+// # Limitations
+//
+// The robot has no disabled state for records: neither the zone export nor the write actions carry such an
+// attribute, and a record is either stored or deleted. The provider therefore offers no way to disable a
+// record without deleting it, and Capabilities reports DisableRecords as false; to roll back in stages,
+// delete the records and keep them to append them again later.
+//
+// The robot reports the SOA with every zone export, which GetSOA returns, but has no action to change it.
+// Neither the zone TTL, the SOA MTTL, nor the refresh, retry and expire timers can therefore be set through
+// the provider, and Capabilities reports ZoneTTL and SOATimers as false; set the TTL of the records
+// instead, which every write sends.
+//
+// # Testing
+//
+// *Provider implements libdns.RecordGetter, libdns.RecordAppender, libdns.RecordSetter,
+// libdns.RecordDeleter and libdns.ZoneLister. Code using the provider should accept these interfaces, so
+// its tests can substitute a fake for the provider.
+//
+// To exercise the provider itself without the live robot, point Endpoint at a robottest.Server replaying
+// recorded exchanges, or set HTTPClient to a client whose Transport answers the requests. Every request of
+// the provider goes through HTTPClient, so its transport sees the exact XML the robot would receive.
+package libdns_kyberio
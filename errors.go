@@ -0,0 +1,177 @@
+package libdns_kyberio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrUnexpectedStatusCode is returned when the robot answers with an HTTP status other than 200.
+	ErrUnexpectedStatusCode = errors.New("unexpected status code")
+
+	// ErrRequestFailed is returned when the robot answers, but reports a failure status for the action.
+	ErrRequestFailed = errors.New("request failed")
+
+	// ErrAuthFailed is returned when the robot rejects the DDNS key.
+	ErrAuthFailed = errors.New("authentication failed")
+
+	// ErrZoneNotFound is returned when the robot does not know the zone, or no zone contains a hostname.
+	ErrZoneNotFound = errors.New("zone not found")
+
+	// ErrQuotaExceeded is returned when the robot rejects a request because a quota or limit is reached.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrMissingStatus is returned when the robot answers with a body that lacks the status attribute, e.g. an
+	// error page of a proxy or a truncated document.
+	ErrMissingStatus = errors.New("response without status")
+
+	// ErrRecordNotFound is returned when a record to change does not exist in the zone.
+	ErrRecordNotFound = errors.New("record not found")
+
+	// ErrRecordExists is returned by CreateRecords if a record to create exists in the zone already.
+	ErrRecordExists = errors.New("record already exists")
+
+	// ErrPreconditionFailed is returned by CompareAndSwapRecord if the record does not hold the expected value.
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	// ErrTooManyDeletes is returned by SetRecords, before anything is written, if it would delete more records
+	// than MaxDeletes allows.
+	ErrTooManyDeletes = errors.New("too many deletes")
+
+	// ErrNoZone is returned if a method is called without a zone and the provider has no DefaultZone.
+	ErrNoZone = errors.New("no zone given and no default zone configured")
+
+	// ErrInvalidRecord is returned before sending a request if a record is invalid.
+	ErrInvalidRecord = errors.New("invalid record")
+
+	// ErrIncompleteResponse is returned when the robot confirms a write without reporting every submitted record.
+	ErrIncompleteResponse = errors.New("incomplete response")
+
+	// ErrNotConfirmed is returned in confirm mode if written records are missing from the zone afterwards.
+	ErrNotConfirmed = errors.New("records not found after write")
+
+	// ErrNoKeyMaterial is returned by GetDS if the zone has no DNSSEC keys the DS records could be derived from.
+	ErrNoKeyMaterial = errors.New("no DNSSEC key material")
+
+	// ErrZeroTTL is returned with RejectZeroTTL set if a zone reports an SOA MTTL of 0.
+	ErrZeroTTL = errors.New("zone TTL is zero")
+
+	// ErrRequestTooLarge is returned before sending a request whose body exceeds MaxRequestBytes.
+	ErrRequestTooLarge = errors.New("request too large")
+
+	// ErrResponseTooLarge is returned if a response body exceeds MaxResponseBytes.
+	ErrResponseTooLarge = errors.New("response too large")
+)
+
+// statusErrors maps the status values of the robot to sentinel errors. Statuses are matched
+// case-insensitively:
+//
+//	ok, found                                 success
+//	denied, unauthorized, forbidden, authfailed  ErrAuthFailed
+//	notfound, nozone                          ErrZoneNotFound
+//	quota, limit                              ErrQuotaExceeded
+//	invalid                                   ErrInvalidRecord
+//	error and any unknown status              ErrRequestFailed
+//	no status at all                          ErrMissingStatus
+var statusErrors = map[string]error{
+	"denied":       ErrAuthFailed,
+	"unauthorized": ErrAuthFailed,
+	"forbidden":    ErrAuthFailed,
+	"authfailed":   ErrAuthFailed,
+	"notfound":     ErrZoneNotFound,
+	"nozone":       ErrZoneNotFound,
+	"quota":        ErrQuotaExceeded,
+	"limit":        ErrQuotaExceeded,
+	"invalid":      ErrInvalidRecord,
+	"error":        ErrRequestFailed,
+}
+
+// statusError returns the sentinel error for a failure status of the robot. Unknown statuses map to
+// ErrRequestFailed.
+func statusError(status string) error {
+	if err, ok := statusErrors[strings.ToLower(status)]; ok {
+		return err
+	}
+	return ErrRequestFailed
+}
+
+// isAuthStatus reports whether the robot status indicates a rejected DDNS key.
+func isAuthStatus(status string) bool {
+	return statusErrors[strings.ToLower(status)] == ErrAuthFailed
+}
+
+// APIError describes a server-side failure of a robot request.
+// It unwraps to the sentinel error matching the HTTP status or the robot status, e.g. ErrAuthFailed,
+// ErrUnexpectedStatusCode or ErrRequestFailed, so it can be checked with errors.Is.
+type APIError struct {
+	Action     string // Robot action of the request, e.g. ADDORUPDATERR
+	Status     string // Status attribute reported by the robot, empty on HTTP errors or if the response has none
+	StatusCode int    // HTTP status code of the response
+	Body       string // Raw response body
+	RequestID  string // Correlation ID sent in the X-Request-ID header
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	var msg string
+	if e.StatusCode != http.StatusOK {
+		msg = fmt.Sprintf("%s: unexpected status code: %d", e.Action, e.StatusCode)
+	} else if e.Status == "" {
+		// without a status, the body is the only hint at what answered
+		msg = fmt.Sprintf("%s: unexpected response without status: %q", e.Action, truncate(e.Body, maxErrorBody))
+	} else {
+		msg = fmt.Sprintf("%s: request failed with status %q", e.Action, e.Status)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	return msg
+}
+
+// Unwrap returns the sentinel error matching the failure, see statusErrors for the robot statuses.
+func (e *APIError) Unwrap() error {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return ErrAuthFailed
+	}
+	if e.StatusCode != http.StatusOK {
+		return ErrUnexpectedStatusCode
+	}
+	if e.Status == "" {
+		return ErrMissingStatus
+	}
+	return statusError(e.Status)
+}
+
+// maxErrorBody is the number of bytes of a response body quoted in an error message.
+const maxErrorBody = 200
+
+// truncate shortens s to at most n bytes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// IncompleteResponseError is returned when the robot answers a write with status ok, but does not report
+// every submitted record, so some of them may have been dropped silently. It unwraps to ErrIncompleteResponse.
+type IncompleteResponseError struct {
+	Action  string           // Robot action of the request
+	Missing []ResourceRecord // Submitted records absent from the response
+}
+
+// Error implements the error interface.
+func (e *IncompleteResponseError) Error() string {
+	missing := make([]string, 0, len(e.Missing))
+	for _, record := range e.Missing {
+		missing = append(missing, fmt.Sprintf("%s %s %q", record.Host, record.Type, record.Value))
+	}
+	return fmt.Sprintf("%s: response lacks %d submitted records: %s", e.Action, len(e.Missing), strings.Join(missing, ", "))
+}
+
+// Unwrap returns ErrIncompleteResponse.
+func (e *IncompleteResponseError) Unwrap() error {
+	return ErrIncompleteResponse
+}
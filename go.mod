@@ -2,4 +2,9 @@ module github.com/dhostx/libdns_kyberio
 
 go 1.22.2
 
-require github.com/libdns/libdns v1.1.0
+require (
+	github.com/libdns/libdns v1.1.0
+	golang.org/x/net v0.34.0
+)
+
+require golang.org/x/text v0.21.0 // indirect
@@ -0,0 +1,49 @@
+package libdns_kyberio
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile converts between U-labels and A-labels. It maps names like a lookup, so München and münchen
+// yield the same A-label, but allows the underscores and wildcards that host names of records carry.
+var idnaProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.Transitional(false))
+
+// asciiName converts an internationalized name like münchen.de into its A-label form xn--mnchen-3ya.de,
+// which the robot expects. ASCII names, and names that cannot be converted, are returned unchanged, so the
+// robot reports invalid names as before.
+func asciiName(name string) string {
+	if isASCII(name) {
+		return name
+	}
+	ascii, err := idnaProfile.ToASCII(name)
+	if err != nil {
+		return name
+	}
+	return ascii
+}
+
+// unicodeName converts the A-labels of a name back into U-labels. Names without A-labels, and names that
+// cannot be converted, are returned unchanged.
+func unicodeName(name string) string {
+	if !strings.Contains(strings.ToLower(name), "xn--") {
+		return name
+	}
+	converted, err := idnaProfile.ToUnicode(name)
+	if err != nil {
+		return name
+	}
+	return converted
+}
+
+// isASCII reports whether s consists of ASCII characters only.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
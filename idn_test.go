@@ -0,0 +1,41 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+)
+
+func TestDeleteNameUnicode(t *testing.T) {
+	p, server := newTestProvider(t,
+		zoneExchange(`<rr host="xn--mnchen-3ya" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.2"></rr>`),
+		robottest.Exchange{Action: actionDeleteRR, Response: `<zoneRequest status="ok"><rr host="xn--mnchen-3ya" type="A" value="192.0.2.1" performedAction="deleted"></rr></zoneRequest>`},
+	)
+
+	deleted, err := p.DeleteName(context.Background(), testZone, "münchen")
+	if err != nil {
+		t.Fatalf("DeleteName() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].RR().Name != "xn--mnchen-3ya" {
+		t.Errorf("DeleteName() = %v, want the record of xn--mnchen-3ya", deleted)
+	}
+	deletes := sentRequests(t, server, actionDeleteRR)
+	if len(deletes) != 1 || len(deletes[0].Records) != 1 || deletes[0].Records[0].Host != "xn--mnchen-3ya" {
+		t.Errorf("sent DELRR %+v, want xn--mnchen-3ya only", deletes)
+	}
+}
+
+func TestASCIIName(t *testing.T) {
+	for name, want := range map[string]string{
+		"münchen":             "xn--mnchen-3ya",
+		"www.München.example": "www.xn--mnchen-3ya.example",
+		"xn--mnchen-3ya":      "xn--mnchen-3ya",
+		"_acme-challenge":     "_acme-challenge",
+		"@":                   "@",
+	} {
+		if got := asciiName(name); got != want {
+			t.Errorf("asciiName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
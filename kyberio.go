@@ -1,18 +1,35 @@
 package libdns_kyberio
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/libdns/libdns"
-	"io"
+	"math"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
 
-const url = "https://robot.s-dns.de:8488/"
+// defaultEndpoint is the robot endpoint used when the provider does not configure one.
+const defaultEndpoint = "https://robot.s-dns.de:8488/"
+
+// Robot actions
+const (
+	actionGetZone       = "GETZONE"
+	actionAddOrUpdateRR = "ADDORUPDATERR"
+	actionDeleteRR      = "DELRR"
+	actionGetRootZone   = "getRootZone"
+)
+
+// pingHostname is looked up by Ping. The lookup has no side effects, whether the key manages it or not.
+const pingHostname = "robot.s-dns.de"
+
+// defaultProvider backs the package-level functions, so they share one HTTP client.
+var defaultProvider Provider
 
 type GetRootZoneRequest struct {
 	XMLName  xml.Name `xml:"zoneRequest"`
@@ -37,6 +54,7 @@ type ZoneRequest struct {
 type Zone struct {
 	Name     string           `xml:"name,attr"`               // Zone name attribute
 	Action   string           `xml:"action,attr,omitempty"`   // Zone action attribute
+	Type     string           `xml:"type,attr,omitempty"`     // Record type filter (GETZONE)
 	DDNSKey  string           `xml:"ddnskey,attr,omitempty"`  // Zone key attribute
 	Reseller string           `xml:"reseller,attr,omitempty"` // Resellername (zoneexport)
 	DNSSec   bool             `xml:"dnssec,attr,omitempty"`   // is dnssec active (zoneexport)
@@ -52,6 +70,21 @@ type ResourceRecord struct {
 	Value           string `xml:"value,attr"`                     // Value attribute (e.g., IP address or TXT value)
 	KeepExisting    bool   `xml:"keepExisting,attr,omitempty"`    // Keep existing records flag
 	PerformedAction string `xml:"performedAction,attr,omitempty"` // Optional: Response action (e.g., "updated")
+	Class           string `xml:"class,attr,omitempty"`           // Optional: Record class, IN if empty
+	TTL             int    `xml:"ttl,attr,omitempty"`             // Optional: TTL in seconds, the zone TTL if empty
+	Comment         string `xml:"comment,attr,omitempty"`         // Optional: Note on the record, ignored when matching records
+	Warning         string `xml:"warning,attr,omitempty"`         // Optional: Response warning, e.g. a record shadowed by a wildcard
+}
+
+// defaultClass is the class of records without a class attribute.
+const defaultClass = "IN"
+
+// RecordClass returns the class of the record, IN if the robot did not send one.
+func (r ResourceRecord) RecordClass() string {
+	if r.Class == "" {
+		return defaultClass
+	}
+	return strings.ToUpper(r.Class)
 }
 
 // Struct for XML Response
@@ -71,119 +104,276 @@ type SOA struct {
 	MTTL    int `xml:"mttl,attr"`
 }
 
-// doRequest sends an HTTP request and returns the response body as bytes or an error.
-// It ensures the response body is closed after reading and checks for non-OK status codes.
-func doRequest(request *http.Request) ([]byte, error) {
-	client := &http.Client{}
-	response, err := client.Do(request)
+// doRequest sends an HTTP request for the given robot action and returns the response body as bytes or an error.
+// It tags the request with a correlation ID, ensures the response body is closed after reading and returns an
+// *APIError for non-OK status codes. Requests failing with a network error, 429 or a 5xx status are sent again
+// up to MaxRetries times, as long as the retry budget of the operation lasts. A request rejected for a key
+// supplied by KeyProvider is sent once more with a fresh key. With ExpectContinue, large requests ask for a
+// 100 Continue response before the body is sent, and are sent again without it if the server answers 417.
+func (p *Provider) doRequest(request *http.Request, action string) ([]byte, error) {
+	if request.Header.Get(requestIDHeader) == "" {
+		request.Header.Set(requestIDHeader, requestID(request.Context()))
+	}
+	request.Header.Set("Content-Type", p.contentType())
+
+	expectContinue := p.ExpectContinue && request.ContentLength >= expectContinueMinBytes
+	if expectContinue {
+		request.Header.Set("Expect", "100-continue")
+	}
+
+	body, err := p.sendWithRetries(request, action)
+	var apiErr *APIError
+	if expectContinue && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusExpectationFailed {
+		// the server does not support the header, send the request plainly
+		if request, err = rewind(request); err != nil {
+			return nil, fmt.Errorf("error rewinding request body: %w", err)
+		}
+		request.Header.Del("Expect")
+		body, err = p.sendWithRetries(request, action)
+	}
+	if p.KeyProvider != nil && authFailed(body, err) {
+		if rotated, ok := p.rotateKey(request); ok {
+			return p.sendWithRetries(rotated, action)
+		}
+	}
+	return body, err
+}
+
+// authFailed reports whether a request was rejected for its DDNS key, by HTTP status or response status.
+func authFailed(body []byte, err error) bool {
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+		return errors.Is(err, ErrAuthFailed)
 	}
-	defer response.Body.Close()
+	response, err := decodeZoneResponse(body)
+	return err == nil && isAuthStatus(response.Status)
+}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", response.StatusCode)
+// sendWithRetries sends request, retrying transient failures as described for doRequest.
+func (p *Provider) sendWithRetries(request *http.Request, action string) ([]byte, error) {
+	ctx := request.Context()
+	for retry := 1; ; retry++ {
+		body, err := p.sendRequest(request, action)
+		if err == nil || retry > p.MaxRetries || !canRewind(request) || !isTransient(ctx, err) || !takeRetry(ctx) {
+			return body, err
+		}
+
+		p.debug(ctx, "retrying robot request", "action", action, "retry", retry, "error", err)
+		if err := p.sleep(ctx, p.retryDelay(retry)); err != nil {
+			return nil, err
+		}
+		if request, err = rewind(request); err != nil {
+			return nil, fmt.Errorf("error rewinding request body: %w", err)
+		}
+	}
+}
+
+// sendRequest sends request once and returns the response body, or an *APIError for non-OK status codes.
+func (p *Provider) sendRequest(request *http.Request, action string) ([]byte, error) {
+	p.debug(request.Context(), "sending robot request", "action", action, "request_id", request.Header.Get(requestIDHeader))
+	response, err := p.httpClient().Do(request)
+	if err != nil {
+		if isCertificateError(err) {
+			return nil, fmt.Errorf("error making request: TLS certificate of the robot not accepted: %w", err)
+		}
+		return nil, fmt.Errorf("error making request: %w", err)
 	}
+	defer response.Body.Close()
+	p.debug(request.Context(), "received robot response", "action", action, "status_code", response.StatusCode)
 
-	body, err := io.ReadAll(response.Body)
+	body, err := readBody(request.Context(), response.Body, p.maxResponseBytes())
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, &APIError{
+			Action:     action,
+			StatusCode: response.StatusCode,
+			Body:       string(body),
+			RequestID:  request.Header.Get(requestIDHeader),
+		}
 	}
 
 	return body, nil
+}
+
+// Actions reported for a record in a ChangeResult. Added, updated and deleted are reported by the robot
+// as performedAction, unchanged marks records that already held the requested value and existing marks
+// appended records that the zone already held. Not found marks records to delete that the zone did not hold.
+const (
+	ActionAdded     = "added"
+	ActionUpdated   = "updated"
+	ActionDeleted   = "deleted"
+	ActionUnchanged = "unchanged"
+	ActionExisting  = "existing"
+	ActionNotFound  = "notfound"
+)
+
+// ChangeResult is the outcome of a write operation for a single record.
+type ChangeResult struct {
+	Record  libdns.RR // The record as it is stored in the zone
+	Action  string    // One of the Action constants, or the raw performedAction of the robot
+	Warning string    // Warning the robot reported for the record, if any; the change was made regardless
+}
 
+// resultAction maps the performedAction of the robot to the action of a ChangeResult.
+// A missing action or one of the robot's no-op actions means the record was left unchanged.
+func resultAction(performedAction string) string {
+	switch strings.ToLower(performedAction) {
+	case "", "none", "nochange", ActionUnchanged:
+		return ActionUnchanged
+	default:
+		return performedAction
+	}
 }
 
 type ZoneExport struct {
-	records []ResourceRecord
-	ttl     int
+	records  []ResourceRecord
+	ttl      int
+	soa      SOA
+	reseller string
+	dnssec   bool
 }
 
 // getZone retrieves and parses zone information using the provided context, DDNS key, and zone name.
 // It returns the ZoneExport containing records and TTL, or an error if the operation fails.
-func getZone(ctx context.Context, ddnsKey string, zoneName string) (export ZoneExport, e error) {
+func (p *Provider) getZone(ctx context.Context, ddnsKey string, zoneName string) (export ZoneExport, e error) {
+	return p.getZoneByType(ctx, ddnsKey, zoneName, "")
+}
 
-	// Prepare XML
-	requestData := ZoneRequest{
-		Zone: Zone{
-			Name:    zoneName,
-			Action:  "GETZONE",
-			DDNSKey: ddnsKey,
-		},
-	}
-	xmlData, err := xml.MarshalIndent(requestData, "", "  ")
+// getZoneByType works like getZone, but only returns the records of the given type unless rtype is empty.
+// The type is sent along as a filter; since the robot may ignore it, the records are filtered here as well.
+// A zone TTL of zero is handled as described at zoneTTL.
+func (p *Provider) getZoneByType(ctx context.Context, ddnsKey string, zoneName string, rtype string) (export ZoneExport, e error) {
+	export, err := p.readZone(ctx, ddnsKey, zoneName, rtype)
 	if err != nil {
-		return ZoneExport{}, fmt.Errorf("error marshaling XML: %v", err)
+		return ZoneExport{}, err
 	}
 
-	// Submit the request
-	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(xmlData))
-	if err != nil {
-		return ZoneExport{}, fmt.Errorf("error making POST request: %v", err)
+	if export.ttl, err = p.zoneTTL(ctx, zoneName, export.ttl); err != nil {
+		return ZoneExport{}, err
+	}
+
+	return export, nil
+}
+
+// zoneTTL returns the TTL of records without their own TTL for a zone reporting the given MTTL. An MTTL of
+// zero is replaced by DefaultTTL, rejected with RejectZeroTTL, or logged as a warning.
+func (p *Provider) zoneTTL(ctx context.Context, zoneName string, mttl int) (int, error) {
+	if mttl != 0 {
+		return mttl, nil
 	}
+	// an MTTL of zero gives every record without its own TTL a TTL of zero, which is hardly intended
+	switch {
+	case p.DefaultTTL > 0:
+		p.debug(ctx, "zone reports an SOA MTTL of 0, using DefaultTTL", "zone", zoneName, "ttl", p.DefaultTTL)
+		return int(p.DefaultTTL / time.Second), nil
+	case p.RejectZeroTTL:
+		return 0, fmt.Errorf("%w: zone %s reports an SOA MTTL of 0; fix the SOA or set DefaultTTL", ErrZeroTTL, zoneName)
+	default:
+		p.warn(ctx, "zone reports an SOA MTTL of 0, so records without their own TTL get a TTL of 0; fix the SOA at the robot or set DefaultTTL", "zone", zoneName)
+		return 0, nil
+	}
+}
 
-	body, err := doRequest(request)
+// readZone fetches and decodes the zone export like getZoneByType, but returns the MTTL of the zone as
+// reported, even if it is zero.
+func (p *Provider) readZone(ctx context.Context, ddnsKey string, zoneName string, rtype string) (ZoneExport, error) {
+	body, err := p.fetchZone(ctx, ddnsKey, zoneName, rtype)
 	if err != nil {
 		return ZoneExport{}, err
 	}
 
-	var response Zone
-	err = xml.Unmarshal(body, &response)
+	response, err := decodeZoneResponse(body)
 	if err != nil {
 		return ZoneExport{}, fmt.Errorf("error unmarshaling XML response: %v", err)
 	}
 
+	// the robot reports hosts relative or fully qualified, depending on the zone
 	retvalue := ZoneExport{
-		records: response.Records,
-		ttl:     response.SOA.MTTL,
+		records:  relativeHosts(response.Records, zoneName),
+		ttl:      clampTTL(response.soa().MTTL),
+		soa:      response.soa(),
+		reseller: response.Reseller,
+		dnssec:   response.DNSSec,
+	}
+	if rtype != "" {
+		retvalue.records = filterType(retvalue.records, rtype)
 	}
 
 	return retvalue, nil
 }
 
+// fetchZone sends a GETZONE request, filtered by rtype unless it is empty, and returns the raw response body.
+// A response status other than ok is returned as an *APIError, like for every other zone action.
+func (p *Provider) fetchZone(ctx context.Context, ddnsKey string, zoneName string, rtype string) ([]byte, error) {
+	return p.doRaw(ctx, Zone{
+		Name:    zoneName,
+		Action:  actionGetZone,
+		DDNSKey: ddnsKey,
+		Type:    strings.ToUpper(rtype),
+	})
+}
+
 // GetRootZone retrieves the root DNS zone name associated with the given hostname using the specified DDNS key.
 // It performs an XML-based HTTP POST request to an external service and parses the response to obtain the zone name.
 // Returns the zone name if found, or an error if the operation fails or the zone is not found.
 func GetRootZone(ddnsKey string, hostname string) (zonename string, err error) {
-	// Create the zoneRequest
-	requestData := GetRootZoneRequest{
-		Action:   "getRootZone",
-		DDNSKey:  ddnsKey,
-		Hostname: hostname,
-	}
+	return GetRootZoneContext(context.Background(), ddnsKey, hostname)
+}
 
-	// Marshal the request into XML
-	xmlData, err := xml.Marshal(requestData)
+// GetRootZoneContext works like GetRootZone, but sends the request with the given context.
+// HTTP-level failures are reported as an *APIError, like for all other robot requests.
+func GetRootZoneContext(ctx context.Context, ddnsKey string, hostname string) (zonename string, err error) {
+	return defaultProvider.getRootZone(ctx, ddnsKey, hostname)
+}
+
+// getRootZone implements GetRootZoneContext using the HTTP client of the provider.
+func (p *Provider) getRootZone(ctx context.Context, ddnsKey string, hostname string) (zonename string, err error) {
+	response, err := p.lookupRootZone(ctx, p.rootZoneEndpoint(), ddnsKey, hostname)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling XML: %v", err)
+		return "", err
 	}
 
-	// Make the POST request
-	resp, err := http.Post(url, "application/xml", bytes.NewReader(xmlData))
-	if err != nil {
-		return "", fmt.Errorf("error making POST request: %v", err)
+	// Check if the zone was found
+	if response.Status != "found" {
+		return "", fmt.Errorf("%w for hostname %s", ErrZoneNotFound, hostname)
+	}
+
+	return response.Zonename, nil
+}
+
+// lookupRootZone sends a getRootZone request for hostname to endpoint and returns the parsed response.
+// A response status rejecting the DDNS key is returned as an *APIError.
+func (p *Provider) lookupRootZone(ctx context.Context, endpoint string, ddnsKey string, hostname string) (GetRootZoneResponse, error) {
+	requestData := GetRootZoneRequest{
+		Action:   actionGetRootZone,
+		DDNSKey:  ddnsKey,
+		Hostname: hostname,
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+	body, requestID, err := p.post(ctx, endpoint, actionGetRootZone, requestData)
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %v", err)
+		return GetRootZoneResponse{}, err
 	}
 
-	// Unmarshal the response XML
 	var response GetRootZoneResponse
-	err = xml.Unmarshal(body, &response)
+	err = unmarshalXML(body, &response)
 	if err != nil {
-		return "", fmt.Errorf("error unmarshaling XML response: %v", err)
+		return GetRootZoneResponse{}, fmt.Errorf("error unmarshaling XML response: %v", err)
 	}
 
-	// Check if the zone was found
-	if response.Status != "found" {
-		return "", fmt.Errorf("zone not found for hostname %s", hostname)
+	if isAuthStatus(response.Status) {
+		return GetRootZoneResponse{}, &APIError{
+			Action:     actionGetRootZone,
+			Status:     response.Status,
+			StatusCode: http.StatusOK,
+			Body:       string(body),
+			RequestID:  requestID,
+		}
 	}
 
-	return response.Zonename, nil
+	return response, nil
 }
 
 // AddOrUpdateRR sends a request to add or update DNS resource records in a specified zone based on provided inputs.
@@ -191,220 +381,855 @@ func GetRootZone(ddnsKey string, hostname string) (zonename string, err error) {
 // keepExisting (flag to retain or overwrite existing records).
 // Returns: A slice of updated or added resource records and an error if the operation fails.
 func AddOrUpdateRR(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record, keepExisting bool) ([]ResourceRecord, error) {
+	return defaultProvider.addOrUpdateRR(ctx, ddnsKey, zoneName, records, keepExisting)
+}
+
+// addOrUpdateRR implements AddOrUpdateRR using the HTTP client of the provider.
+func (p *Provider) addOrUpdateRR(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record, keepExisting bool) ([]ResourceRecord, error) {
 	// Create the request object
 	var recordsToAppend []ResourceRecord
 
 	for _, record := range records {
-		var rec = record.RR()
-		recordsToAppend = append(recordsToAppend, ResourceRecord{
-			Host:         rec.Name,
-			Type:         rec.Type,
-			Value:        rec.Data,
-			KeepExisting: keepExisting,
-		})
-
+		rr := toResourceRecord(record, zoneName)
+		rr.KeepExisting = keepExisting
+		recordsToAppend = append(recordsToAppend, rr)
 	}
 
-	request := ZoneRequest{
-		Zone: Zone{
-			Name:    zoneName,
-			Action:  "ADDORUPDATERR", // Action based on your request
-			DDNSKey: ddnsKey,
-			Records: recordsToAppend,
-		},
-	}
+	return p.addOrUpdateResourceRecords(ctx, ddnsKey, zoneName, recordsToAppend)
+}
 
-	// Marshal the request object to XML
-	xmlData, err := xml.MarshalIndent(request, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal XML: %w", err)
+// AddOrUpdateResourceRecords sends a request to add or update DNS resource records in a specified zone.
+// Unlike AddOrUpdateRR, the KeepExisting flag of every record is sent as given, so a single request
+// can append some records while overwriting others.
+// Returns: A slice of updated or added resource records and an error if the operation fails.
+func AddOrUpdateResourceRecords(ctx context.Context, ddnsKey string, zoneName string, records []ResourceRecord) ([]ResourceRecord, error) {
+	return defaultProvider.addOrUpdateResourceRecords(ctx, ddnsKey, zoneName, records)
+}
+
+// addOrUpdateResourceRecords implements AddOrUpdateResourceRecords using the HTTP client of the provider.
+// The zone TTL, which MinTTL is applied to, is only looked up if a record lacks a TTL.
+func (p *Provider) addOrUpdateResourceRecords(ctx context.Context, ddnsKey string, zoneName string, records []ResourceRecord) ([]ResourceRecord, error) {
+	zoneTTL := 0
+	if p.MinTTL > 0 && lacksTTL(records) {
+		zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+		if err != nil {
+			return nil, err
+		}
+		zoneTTL = zoneExport.ttl
 	}
+	return p.writeResourceRecords(ctx, ddnsKey, zoneName, records, zoneTTL)
+}
 
-	// Add the XML header
-	xmlHeader := []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\n")
-	finalXML := append(xmlHeader, xmlData...)
+// writeResourceRecords sends the records with ADDORUPDATERR, raising their TTLs to MinTTL for a zone with
+// the given TTL, which callers that read the zone anyway pass along.
+func (p *Provider) writeResourceRecords(ctx context.Context, ddnsKey string, zoneName string, records []ResourceRecord, zoneTTL int) (results []ResourceRecord, err error) {
+	defer func() { p.stats.count(results, err) }()
 
-	// Create a new HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(finalXML))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	// nothing to do, e.g. a set against an empty input
+	if len(records) == 0 {
+		return nil, nil
 	}
-	req.Header.Set("Content-Type", "application/xml")
 
-	respBody, err := doRequest(req)
-	if err != nil {
+	records = p.clampTTLs(p.hostCase(relativeHosts(records, zoneName)), zoneTTL)
+	if err := validateWrite(records, zoneName); err != nil {
 		return nil, err
 	}
 
-	var response ZoneResponse
-	err = xml.Unmarshal(respBody, &response)
+	response, err := p.do(ctx, Zone{
+		Name:    zoneName,
+		Action:  actionAddOrUpdateRR,
+		DDNSKey: ddnsKey,
+		Records: records,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+		return nil, err
 	}
 
-	if strings.EqualFold(response.Status, "ok") {
-		return response.Records, nil
+	p.invalidateZone(ddnsKey, zoneName)
+	p.logWarnings(ctx, actionAddOrUpdateRR, zoneName, response)
+	response.Records = relativeHosts(response.Records, zoneName)
+	if missing := missingRecords(records, response.Records); len(missing) > 0 {
+		return response.Records, &IncompleteResponseError{Action: actionAddOrUpdateRR, Missing: missing}
 	}
-
-	return nil, fmt.Errorf("failed to add or update records: %s", response.Status)
+	if p.Confirm {
+		if err := p.confirmRecords(ctx, ddnsKey, zoneName, records); err != nil {
+			return response.Records, err
+		}
+	}
+	return response.Records, nil
 }
 
 // DeleteRR deletes specified resource records from a DNS zone using the provided ddnsKey and zoneName.
 // It sends a POST request with the required XML payload and returns the deleted resource records or an error.
 func DeleteRR(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (deletedRRs []ResourceRecord, err error) {
+	return defaultProvider.deleteRR(ctx, ddnsKey, zoneName, records)
+}
+
+// deleteRR implements DeleteRR using the HTTP client of the provider.
+func (p *Provider) deleteRR(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (deletedRRs []ResourceRecord, err error) {
 	recordsToDelete := []ResourceRecord{}
 	for _, record := range records {
-		var rec = record.RR()
-		recordsToDelete = append(recordsToDelete,
-			ResourceRecord{
-				Host:  rec.Name,
-				Type:  rec.Type,
-				Value: rec.Data,
-			})
-	}
-	request := ZoneRequest{
-		Zone: Zone{
-			Name:    zoneName,
-			Action:  "DELRR",
-			Records: recordsToDelete,
-			DDNSKey: ddnsKey,
-		},
-	}
-
-	xmlData, err := xml.MarshalIndent(request, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal XML: %w", err)
+		recordsToDelete = append(recordsToDelete, toResourceRecord(record, zoneName))
 	}
+	return p.deleteResourceRecords(ctx, ddnsKey, zoneName, recordsToDelete)
+}
 
-	xmlHeader := []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\n")
-	finalXML := append(xmlHeader, xmlData...)
+// deleteResourceRecords sends a DELRR request for the given robot records and returns the records reported by the robot.
+func (p *Provider) deleteResourceRecords(ctx context.Context, ddnsKey string, zoneName string, recordsToDelete []ResourceRecord) (deletedRRs []ResourceRecord, err error) {
+	defer func() { p.stats.count(deletedRRs, err) }()
 
-	resp, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(finalXML))
-	if err != nil {
-		return nil, fmt.Errorf("error making POST request: %v", err)
+	// nothing to do, e.g. a delete by name and type in a zone without matching records
+	if len(recordsToDelete) == 0 {
+		return nil, nil
 	}
+	recordsToDelete = p.hostCase(relativeHosts(recordsToDelete, zoneName))
 
-	respBody, err := doRequest(resp)
+	response, err := p.do(ctx, Zone{
+		Name:    zoneName,
+		Action:  actionDeleteRR,
+		DDNSKey: ddnsKey,
+		Records: recordsToDelete,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var response ZoneResponse
-	err = xml.Unmarshal(respBody, &response)
+	p.invalidateZone(ddnsKey, zoneName)
+	p.logWarnings(ctx, actionDeleteRR, zoneName, response)
+	return relativeHosts(response.Records, zoneName), nil
+}
+
+// missingRecords returns the submitted records that are absent from the records reported by the robot.
+func missingRecords(submitted []ResourceRecord, reported []ResourceRecord) []ResourceRecord {
+	var missing []ResourceRecord
+	for _, record := range submitted {
+		if !containsRecord(reported, record) {
+			missing = append(missing, record)
+		}
+	}
+	return missing
+}
+
+// confirmRecords fetches the zone and checks that every record is present with the expected value.
+// It returns an error wrapping ErrNotConfirmed naming the records that are missing.
+func (p *Provider) confirmRecords(ctx context.Context, ddnsKey string, zoneName string, records []ResourceRecord) error {
+	zoneExport, err := p.getZone(ctx, ddnsKey, zoneName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+		return fmt.Errorf("confirming records: %w", err)
 	}
 
-	return response.Records, nil
+	var missing []string
+	for _, record := range records {
+		if !containsRecord(zoneExport.records, record) {
+			missing = append(missing, fmt.Sprintf("%s %s %q", record.Host, record.Type, record.Value))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrNotConfirmed, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// toResourceRecord converts a libdns record into the <rr> representation used by the robot.
+// Names are made relative to the zone and addresses are normalized, so the robot always receives
+// their canonical form. TTLs are clamped to the valid range.
+func toResourceRecord(record libdns.Record, zoneName string) ResourceRecord {
+	rec := record.RR()
+	return ResourceRecord{
+		Host:  relativeHost(asciiName(rec.Name), zoneName),
+		Type:  strings.ToUpper(rec.Type),
+		Value: wireValue(rec.Type, rec.Data),
+		TTL:   clampTTL(int(rec.TTL / time.Second)),
+	}
+}
+
+// clampTTLs limits the TTLs of records to the valid range and raises them to MinTTL, see clampTTL and floorTTL.
+func (p *Provider) clampTTLs(records []ResourceRecord, zoneTTL int) []ResourceRecord {
+	for i := range records {
+		records[i].TTL = p.floorTTL(clampTTL(records[i].TTL), zoneTTL)
+	}
+	return records
+}
+
+// floorTTL returns the TTL to write for a record with the given TTL in a zone with the given TTL, both in
+// seconds. A TTL below MinTTL is raised to it. A record without a TTL keeps it, so it follows the zone TTL,
+// unless the zone TTL is below MinTTL; then it gets MinTTL as its own TTL.
+func (p *Provider) floorTTL(ttl int, zoneTTL int) int {
+	floor := int(p.MinTTL / time.Second)
+	if ttl <= 0 && zoneTTL >= floor {
+		return ttl
+	}
+	return max(ttl, floor)
+}
+
+// lacksTTL reports whether a record of records has no TTL of its own.
+func lacksTTL(records []ResourceRecord) bool {
+	for _, record := range records {
+		if record.TTL <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// maxTTL is the largest TTL in seconds, RFC 2181 section 8.
+const maxTTL = math.MaxInt32
+
+// clampTTL limits a TTL in seconds, as reported by the robot or requested by the caller, to the valid range,
+// so a huge value does not overflow the TTL arithmetic. Negative values become zero, which stands for the
+// zone TTL or its default.
+func clampTTL(seconds int) int {
+	return min(max(seconds, 0), maxTTL)
+}
+
+// ttlChanged reports whether a desired record asks for a TTL other than the one of the stored record, which
+// has the zone TTL unless it carries its own. A desired record without a TTL accepts any.
+func ttlChanged(desired ResourceRecord, stored ResourceRecord, zoneTTL int) bool {
+	if desired.TTL <= 0 {
+		return false
+	}
+	current := stored.TTL
+	if current <= 0 {
+		current = zoneTTL
+	}
+	return desired.TTL != current
+}
+
+// toLibdnsRR converts a robot record into a libdns.RR with the given TTL, or the TTL of the record if the
+// robot reports one. The type is returned in uppercase, the form used by libdns, whatever case the robot reports,
+// and DS and DNSKEY values in the canonical form they are written in.
+func toLibdnsRR(record ResourceRecord, ttl time.Duration) libdns.RR {
+	if record.TTL > 0 {
+		ttl = time.Duration(clampTTL(record.TTL)) * time.Second
+	}
+	data := record.Value
+	switch strings.ToUpper(record.Type) {
+	case "TXT", "DS", "DNSKEY":
+		// the robot may report the character-strings of a TXT record quoted, and key data split into blocks
+		data = normalizeValue(record.Type, data)
+	}
+	return libdns.RR{
+		Name: record.Host,
+		Type: strings.ToUpper(record.Type),
+		Data: data,
+		TTL:  ttl,
+	}
+}
+
+// sameRecord reports whether a and b describe the same record, ignoring flags and actions.
+// Hosts are compared case-insensitively, like DNS names, and values in their normalized form.
+func sameRecord(a ResourceRecord, b ResourceRecord) bool {
+	return strings.EqualFold(a.Host, b.Host) && strings.EqualFold(a.Type, b.Type) && a.RecordClass() == b.RecordClass() &&
+		normalizeValue(a.Type, a.Value) == normalizeValue(b.Type, b.Value)
+}
+
+// storedRecord returns the record of the zone equal to record according to sameRecord, as the robot stores it.
+func storedRecord(records []ResourceRecord, record ResourceRecord) (ResourceRecord, bool) {
+	for _, r := range records {
+		if sameRecord(r, record) {
+			return r, true
+		}
+	}
+	return ResourceRecord{}, false
+}
+
+// containsRecord reports whether records contains a record equal to record according to sameRecord.
+func containsRecord(records []ResourceRecord, record ResourceRecord) bool {
+	for _, r := range records {
+		if sameRecord(r, record) {
+			return true
+		}
+	}
+	return false
 }
 
 // appendRecords appends new DNS records to a specified zone without modifying existing records.
-// It retrieves the zone TTL from the SOA record and returns only the newly added records.
+// It retrieves the zone TTL from the SOA record and returns the newly added records together with the
+// input records that were already present, so appending is idempotent.
 // Parameters: ctx (context), ddnsKey (authentication key), zoneName (zone name), records (DNS records to append).
-// Returns: A slice of newly added DNS records and an error if any occurs during the operation.
-func appendRecords(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (appendedRecords []libdns.Record, err error) {
+// Returns: A slice of added or already present DNS records and an error if any occurs during the operation.
+func (p *Provider) appendRecords(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (appendedRecords []libdns.Record, err error) {
+	results, err := p.appendRecordResults(ctx, ddnsKey, zoneName, records)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		switch result.Action {
+		case ActionAdded, ActionExisting:
+			appendedRecords = append(appendedRecords, result.Record)
+		}
+	}
+
+	return appendedRecords, nil
+}
 
+// appendRecordResults appends DNS records to the zone without modifying existing records and returns the
+// outcome of every record: ActionAdded, or ActionExisting for records the zone already held.
+func (p *Provider) appendRecordResults(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (results []ChangeResult, err error) {
 	// fetch all records to get the SOA -> ttl
-	zoneExport, err := getZone(ctx, ddnsKey, zoneName)
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
 	if err != nil {
 		return nil, err
 	}
+	return p.appendToZone(ctx, ddnsKey, zoneName, zoneExport, records)
+}
+
+// appendToZone works like appendRecordResults for a zone fetched already.
+func (p *Provider) appendToZone(ctx context.Context, ddnsKey string, zoneName string, zoneExport ZoneExport, records []libdns.Record) (results []ChangeResult, err error) {
+	ttl := time.Duration(zoneExport.ttl) * time.Second
+
+	var writes []ResourceRecord
+	for _, record := range records {
+		rr := toResourceRecord(record, zoneName)
+		if containsRecord(zoneExport.records, rr) {
+			// the zone holds it already; it is reported below without being sent
+			continue
+		}
+		rr.KeepExisting = true
+		writes = append(writes, rr)
+	}
+	if err := validateCNAMEs(writes, zoneExport.records); err != nil {
+		return nil, err
+	}
 
 	// perform the update, existing records will not be updated
-	resultRecords, err := AddOrUpdateRR(ctx, ddnsKey, zoneName, records, true)
+	resultRecords, err := p.writeResourceRecords(ctx, ddnsKey, zoneName, writes, zoneExport.ttl)
+	var incomplete *IncompleteResponseError
+	if errors.As(err, &incomplete) {
+		// records the robot kept without reporting them are fine, they are handled below
+		var dropped []ResourceRecord
+		for _, record := range incomplete.Missing {
+			if !containsRecord(zoneExport.records, record) {
+				dropped = append(dropped, record)
+			}
+		}
+		if len(dropped) == 0 {
+			err = nil
+		} else {
+			incomplete.Missing = dropped
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// return only newly added records
 	for _, record := range resultRecords {
-		if record.PerformedAction == "added" {
-			appendedRecords = append(appendedRecords, libdns.RR{
-				Type: record.Type,
-				Name: record.Host,
-				Data: record.Value,
-				TTL:  time.Duration(zoneExport.ttl) * time.Second,
-			})
+		action := resultAction(record.PerformedAction)
+		warning := record.Warning
+		if action == ActionUnchanged {
+			action = ActionExisting
+			// report the record as the zone holds it, including its own TTL
+			if stored, ok := storedRecord(zoneExport.records, record); ok {
+				record = stored
+			}
 		}
+		results = append(results, ChangeResult{
+			Record:  toLibdnsRR(record, ttl),
+			Action:  action,
+			Warning: warning,
+		})
 	}
 
-	return appendedRecords, nil
+	// records that are not echoed back were kept, if the zone already holds them
+	for _, record := range records {
+		rr := toResourceRecord(record, zoneName)
+		stored, ok := storedRecord(zoneExport.records, rr)
+		if !ok || containsRecord(resultRecords, rr) {
+			continue
+		}
+		results = append(results, ChangeResult{
+			Record: toLibdnsRR(stored, ttl),
+			Action: ActionExisting,
+		})
+	}
+
+	return results, nil
+}
+
+// createRecords adds records that must not exist yet. If the zone holds any of them, nothing is written and
+// the error, wrapping ErrRecordExists, names the existing records. Records created concurrently by someone
+// else are reported the same way, along with the records that were added.
+func (p *Provider) createRecords(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (createdRecords []libdns.Record, err error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []string
+	for _, record := range records {
+		rr := toResourceRecord(record, zoneName)
+		if containsRecord(zoneExport.records, rr) {
+			existing = append(existing, fmt.Sprintf("%s %s %q", rr.Host, rr.Type, rr.Value))
+		}
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRecordExists, strings.Join(existing, ", "))
+	}
+
+	results, err := p.appendToZone(ctx, ddnsKey, zoneName, zoneExport, records)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		switch result.Action {
+		case ActionAdded:
+			createdRecords = append(createdRecords, result.Record)
+		case ActionExisting:
+			rr := toResourceRecord(result.Record, zoneName)
+			existing = append(existing, fmt.Sprintf("%s %s %q", rr.Host, rr.Type, rr.Value))
+		}
+	}
+	if len(existing) > 0 {
+		return createdRecords, fmt.Errorf("%w: %s", ErrRecordExists, strings.Join(existing, ", "))
+	}
+
+	return createdRecords, nil
 }
 
-// setRecords updates or adds DNS records in the specified zone and returns only the records that were updated.
-// It fetches the current zone data to determine TTL and updates or adds records using the provided data.
+// setRecords replaces the RRsets of the input records in the specified zone and returns the records that were set.
+// Records that already held the requested value are included, so the result describes the full outcome.
 // ctx is the execution context, ddnsKey is the key for authentication, zoneName specifies the DNS zone,
 // and records is the slice of libdns.Record containing the records to update.
-// Returns a slice of updated libdns.Record and an error if the operation fails.
-func setRecords(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (updatedRecords []libdns.Record, err error) {
-	// fetch all records to get the SOA -> ttl
-	zoneExport, err := getZone(ctx, ddnsKey, zoneName)
+// Returns a slice of set libdns.Record and an error if the operation fails.
+func (p *Provider) setRecords(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (setRecords []libdns.Record, err error) {
+	results, err := p.setRecordResults(ctx, ddnsKey, zoneName, records)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		switch result.Action {
+		case ActionAdded, ActionUpdated, ActionUnchanged:
+			setRecords = append(setRecords, result.Record)
+		}
+	}
+
+	return setRecords, nil
+}
+
+// rrsetPlan is what SetRecords does to one RRset of the zone.
+type rrsetPlan struct {
+	unchanged []ResourceRecord // Current records that are desired as stored
+	retimed   []ResourceRecord // Desired records whose value is current, but not their TTL
+	missing   []ResourceRecord // Desired records whose value is missing
+	extra     []ResourceRecord // Current records that are not desired
+}
+
+// planSet computes what SetRecords does to make the zone hold the desired records, for SetRecords and Diff
+// alike: repeated records are dropped, the set is validated, TTLs are clamped to MinTTL and the records are
+// compared by RRset, value and TTL. Retimed and missing records carry the KeepExisting flag to write them
+// with. It also returns the desired records without repetitions, in input order.
+func (p *Provider) planSet(ctx context.Context, zoneExport ZoneExport, zoneName string, records []libdns.Record) ([]ResourceRecord, []rrsetPlan, error) {
+	var desired []ResourceRecord
+	for _, record := range records {
+		rr := toResourceRecord(record, zoneName)
+		if containsRecord(desired, rr) {
+			p.debug(ctx, "ignoring duplicate record", "host", rr.Host, "type", rr.Type, "value", rr.Value)
+			continue
+		}
+		desired = append(desired, rr)
+	}
+	if err := validateSet(desired); err != nil {
+		return nil, nil, err
+	}
+
+	// clamp here already, so a TTL raised to MinTTL compares equal to the stored one
+	desired = p.clampTTLs(desired, zoneExport.ttl)
+
+	var plans []rrsetPlan
+	var writes []ResourceRecord
+	for _, set := range groupRRsets(desired, zoneExport.records) {
+		var plan rrsetPlan
+		keepExisting := len(set.desired) > 1
+		for _, record := range set.present() {
+			stored, _ := storedRecord(set.current, record)
+			if ttlChanged(record, stored, zoneExport.ttl) {
+				// the value is current, but the TTL is not; write it again to apply the TTL
+				record.KeepExisting = keepExisting
+				plan.retimed = append(plan.retimed, record)
+				continue
+			}
+			plan.unchanged = append(plan.unchanged, stored)
+		}
+		for _, record := range set.missing() {
+			record.KeepExisting = keepExisting
+			plan.missing = append(plan.missing, record)
+		}
+		plan.extra = set.extra()
+		writes = append(append(writes, plan.retimed...), plan.missing...)
+		plans = append(plans, plan)
+	}
+
+	if err := validateCNAMEs(writes, zoneExport.records); err != nil {
+		return nil, nil, err
+	}
+	return desired, plans, nil
+}
+
+// setRecordResults makes the zone hold exactly the given records for every (name, type) pair in the input and
+// returns the outcome of every record. Missing records are written; a single-valued RRset is overwritten in
+// place, a multi-valued one is extended. Records of those RRsets that are not in the input are deleted
+// afterwards and reported with ActionDeleted. Records the zone already holds are not sent and are reported
+// with ActionUnchanged.
+func (p *Provider) setRecordResults(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (results []ChangeResult, err error) {
+	// fetch all records to get the SOA -> ttl and the current RRsets
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
 	if err != nil {
 		return nil, err
 	}
+	ttl := time.Duration(zoneExport.ttl) * time.Second
 
-	// perform the update, existing records will be updated
-	resultRecords, err := AddOrUpdateRR(ctx, ddnsKey, zoneName, records, false)
+	desired, plans, err := p.planSet(ctx, zoneExport, zoneName, records)
 	if err != nil {
 		return nil, err
 	}
 
-	// return only newly added records
+	var writes, extras []ResourceRecord
+	for _, plan := range plans {
+		for _, stored := range plan.unchanged {
+			results = append(results, ChangeResult{Record: toLibdnsRR(stored, ttl), Action: ActionUnchanged})
+		}
+		writes = append(writes, plan.retimed...)
+		writes = append(writes, plan.missing...)
+		extras = append(extras, plan.extra...)
+	}
+
+	if p.MaxDeletes > 0 && len(extras) > p.MaxDeletes {
+		return nil, fmt.Errorf("%w: setting the records would delete %d records, the limit is %d", ErrTooManyDeletes, len(extras), p.MaxDeletes)
+	}
+
+	// write first, so the RRset is never empty in between
+	resultRecords, err := p.writeResourceRecords(ctx, ddnsKey, zoneName, writes, zoneExport.ttl)
+	if err != nil {
+		return nil, err
+	}
 	for _, record := range resultRecords {
-		if record.PerformedAction == "updated" {
-			updatedRecords = append(updatedRecords, libdns.RR{
-				Type: record.Type,
-				Name: record.Host,
-				Data: record.Value,
-				TTL:  time.Duration(zoneExport.ttl) * time.Second,
-			})
+		results = append(results, ChangeResult{
+			Record:  toLibdnsRR(record, ttl),
+			Action:  resultAction(record.PerformedAction),
+			Warning: record.Warning,
+		})
+	}
+	// report the set records in input order, whatever order the robot answered in
+	results = orderByInput(results, desired, zoneName)
+
+	deletedRecords, err := p.deleteResourceRecords(ctx, ddnsKey, zoneName, extras)
+	if err != nil {
+		return results, err
+	}
+	for _, record := range deletedRecords {
+		if record.PerformedAction == ActionDeleted {
+			results = append(results, ChangeResult{Record: toLibdnsRR(record, ttl), Action: ActionDeleted, Warning: record.Warning})
 		}
 	}
 
-	return updatedRecords, nil
+	return results, nil
+}
+
+// orderByInput sorts results into the order of the input records they belong to. Results matching no
+// input record keep their order after the others.
+func orderByInput(results []ChangeResult, input []ResourceRecord, zoneName string) []ChangeResult {
+	position := func(result ChangeResult) int {
+		record := toResourceRecord(result.Record, zoneName)
+		for i, in := range input {
+			if sameRecord(in, record) {
+				return i
+			}
+		}
+		return len(input)
+	}
+	slices.SortStableFunc(results, func(a, b ChangeResult) int {
+		return cmp.Compare(position(a), position(b))
+	})
+	return results
 }
 
 // getRecords retrieves DNS records for a specific zone using the provided DDNS key and zone name.
 // It returns a slice of libdns.Record and an error.
 // The function fetches and parses zone data via getZone, then maps it to the libdns.Record structure.
-func getRecords(ctx context.Context, ddnsKey string, zoneName string) (records []libdns.Record, err error) {
-	zoneExport, err := getZone(ctx, ddnsKey, zoneName)
+// A zone without records yields an empty result, not an error. The order of the robot's response is preserved.
+func (p *Provider) getRecords(ctx context.Context, ddnsKey string, zoneName string) (records []libdns.Record, err error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return nil, err
+	}
+	return p.libdnsRecords(zoneExport), nil
+}
+
+// getRecordsByType retrieves the records of the given type, e.g. the TXT records for an ACME challenge.
+// A cached zone is filtered locally; otherwise only the records of the type are requested from the robot.
+func (p *Provider) getRecordsByType(ctx context.Context, ddnsKey string, zoneName string, rtype string) ([]libdns.Record, error) {
+	var zoneExport ZoneExport
+	var err error
+	if p.ZoneCacheTTL > 0 {
+		zoneExport, err = p.zone(ctx, ddnsKey, zoneName)
+		zoneExport.records = filterType(zoneExport.records, rtype)
+	} else {
+		zoneExport, err = p.getZoneByType(ctx, ddnsKey, zoneName, rtype)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.libdnsRecords(zoneExport), nil
+}
+
+// getRecordComments returns the comments of the records of the zone, keyed by the record as returned by
+// getRecords. Records without a comment are left out.
+func (p *Provider) getRecordComments(ctx context.Context, ddnsKey string, zoneName string) (map[libdns.RR]string, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
 	if err != nil {
 		return nil, err
 	}
 
+	comments := make(map[libdns.RR]string)
 	for _, record := range zoneExport.records {
-		records = append(records, libdns.RR{
-			Name: record.Host,
-			Type: record.Type,
-			Data: record.Value,
-			TTL:  time.Duration(zoneExport.ttl) * time.Second,
-		})
+		if record.Comment != "" {
+			comments[toLibdnsRR(record, time.Duration(zoneExport.ttl)*time.Second)] = record.Comment
+		}
+	}
+	return comments, nil
+}
+
+// parseRecords converts records into the typed structs of libdns, like libdns.Address or libdns.TXT, if
+// ParseRecords is set. Records of types libdns has no struct for, and records whose data does not parse,
+// are kept as libdns.RR rather than dropped or failing the whole operation.
+func (p *Provider) parseRecords(ctx context.Context, records []libdns.Record) []libdns.Record {
+	if !p.ParseRecords {
+		return records
+	}
+	parsed := make([]libdns.Record, 0, len(records))
+	for _, record := range records {
+		rr := record.RR()
+		typed, err := rr.Parse()
+		if err != nil {
+			p.debug(ctx, "keeping unparsable record as RR", "name", rr.Name, "type", rr.Type, "error", err)
+			typed = rr
+		}
+		parsed = append(parsed, typed)
+	}
+	return parsed
+}
+
+// absoluteNames qualifies the names of records relative to zoneName if AbsoluteNames is set.
+func (p *Provider) absoluteNames(records []libdns.Record, zoneName string) []libdns.Record {
+	if !p.AbsoluteNames {
+		return records
+	}
+	suffix := ""
+	if !p.OmitTrailingDot {
+		suffix = "."
+	}
+	zoneName = strings.TrimSuffix(zoneName, ".")
+	qualified := make([]libdns.Record, 0, len(records))
+	for _, record := range records {
+		rr := record.RR()
+		if rr.Name == "" || rr.Name == "@" {
+			rr.Name = zoneName + suffix
+		} else {
+			rr.Name = rr.Name + "." + zoneName + suffix
+		}
+		qualified = append(qualified, rr)
+	}
+	return qualified
+}
+
+// unicodeNames converts the names of records into U-labels if UnicodeNames is set.
+func (p *Provider) unicodeNames(records []libdns.Record) []libdns.Record {
+	if !p.UnicodeNames {
+		return records
+	}
+	converted := make([]libdns.Record, 0, len(records))
+	for _, record := range records {
+		rr := record.RR()
+		rr.Name = unicodeName(rr.Name)
+		converted = append(converted, rr)
+	}
+	return converted
+}
+
+// filterType returns the records of the given type.
+func filterType(records []ResourceRecord, rtype string) []ResourceRecord {
+	var filtered []ResourceRecord
+	for _, record := range records {
+		if strings.EqualFold(record.Type, rtype) {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// libdnsRecords converts the records of a zone export, dropping duplicates if DeduplicateRecords is set.
+func (p *Provider) libdnsRecords(zoneExport ZoneExport) (records []libdns.Record) {
+	var seen map[libdns.RR]bool
+	if p.DeduplicateRecords {
+		seen = make(map[libdns.RR]bool)
+	}
+
+	for _, record := range zoneExport.records {
+		rr := toLibdnsRR(record, time.Duration(zoneExport.ttl)*time.Second)
+		if seen != nil {
+			// keyed on name, type, normalized value and TTL; the first occurrence is kept
+			key := libdns.RR{Name: rr.Name, Type: strings.ToUpper(rr.Type), Data: normalizeValue(rr.Type, rr.Data), TTL: rr.TTL}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		records = append(records, rr)
 	}
-	return records, nil
+	return records
 }
 
 // deleteRecords removes DNS records from the specified zone and returns the deleted records or an error if the operation fails.
-func deleteRecords(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (recordsDeleted []libdns.Record, err error) {
-	deletedRecords, err := DeleteRR(ctx, ddnsKey, zoneName, records)
+// A record with an empty value matches every record of the zone with the same name and type, e.g. to remove
+// all _acme-challenge TXT records of a name in one call. The type must always be given.
+func (p *Provider) deleteRecords(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (recordsDeleted []libdns.Record, err error) {
+	// on a failed batch, the records of the earlier batches are deleted already
+	results, err := p.deleteRecordResults(ctx, ddnsKey, zoneName, records)
+	for _, result := range results {
+		if result.Action == ActionDeleted {
+			recordsDeleted = append(recordsDeleted, result.Record)
+		}
+	}
+
+	return recordsDeleted, err
+}
+
+// deleteRecordResults works like deleteRecords, but returns the outcome of every record in input order:
+// deleted, not found if the zone held no matching record, or the action the robot reported otherwise.
+// A record matching several records of the zone yields one result per matched record.
+// With DeleteBatchSize set, the records are deleted in batches; if a batch fails, the results of the earlier
+// batches are returned along with the error.
+func (p *Provider) deleteRecordResults(ctx context.Context, ddnsKey string, zoneName string, records []libdns.Record) (results []ChangeResult, err error) {
+	// fetch all records to get the SOA -> ttl and to resolve records without a value
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
 	if err != nil {
 		return nil, err
 	}
-	zoneExport, err := getZone(ctx, ddnsKey, zoneName)
+	ttl := time.Duration(zoneExport.ttl) * time.Second
+
+	batchSize := p.DeleteBatchSize
+	if batchSize <= 0 {
+		batchSize = max(len(records), 1)
+	}
+	for start := 0; start < len(records); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		batch := records[start:min(start+batchSize, len(records))]
+
+		expanded := make([][]ResourceRecord, len(batch))
+		var recordsToDelete []ResourceRecord
+		for i, record := range batch {
+			expanded[i] = expandDeletes([]libdns.Record{record}, zoneExport.records, zoneName, zoneExport.ttl, p.MatchTTLOnDelete, p.MatchValueOnDelete)
+			recordsToDelete = append(recordsToDelete, expanded[i]...)
+		}
+
+		deletedRecords, err := p.deleteResourceRecords(ctx, ddnsKey, zoneName, recordsToDelete)
+		if err != nil {
+			return results, err
+		}
+
+		for i, record := range batch {
+			if len(expanded[i]) == 0 {
+				results = append(results, ChangeResult{Record: toLibdnsRR(toResourceRecord(record, zoneName), ttl), Action: ActionNotFound})
+				continue
+			}
+			for _, sent := range expanded[i] {
+				action, warning := ActionNotFound, ""
+				if echoed, ok := storedRecord(deletedRecords, sent); ok && echoed.PerformedAction != "" {
+					action, warning = echoed.PerformedAction, echoed.Warning
+				}
+				results = append(results, ChangeResult{Record: toLibdnsRR(sent, ttl), Action: action, Warning: warning})
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// confirmedDeletes returns the records of sent that the robot reports as deleted in echoed, in the order
+// they were sent and each once. Since sent is derived from the input records in order, the result correlates
+// with the input, whatever order or form the robot echoes the records in.
+func confirmedDeletes(sent []ResourceRecord, echoed []ResourceRecord) []ResourceRecord {
+	var deleted []ResourceRecord
+	for _, record := range echoed {
+		if record.PerformedAction == ActionDeleted {
+			deleted = append(deleted, record)
+		}
+	}
+
+	var confirmed []ResourceRecord
+	for _, record := range sent {
+		if containsRecord(deleted, record) && !containsRecord(confirmed, record) {
+			confirmed = append(confirmed, record)
+		}
+	}
+	return confirmed
+}
+
+// deleteName removes all records of a name, whatever their type, in a single DELRR request and returns the
+// deleted records.
+func (p *Provider) deleteName(ctx context.Context, ddnsKey string, zoneName string, name string) (recordsDeleted []libdns.Record, err error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, record := range deletedRecords {
-		if record.PerformedAction == "deleted" {
-			recordsDeleted = append(recordsDeleted, libdns.RR{
-				Name: record.Host,
-				Type: record.Type,
-				Data: record.Value,
-				TTL:  time.Duration(zoneExport.ttl) * time.Second,
-			})
+	host := relativeHost(asciiName(name), zoneName)
+	var recordsToDelete []ResourceRecord
+	for _, record := range zoneExport.records {
+		if strings.EqualFold(record.Host, host) {
+			recordsToDelete = append(recordsToDelete, ResourceRecord{Host: record.Host, Type: record.Type, Value: record.Value, Class: record.Class})
 		}
 	}
 
+	deletedRecords, err := p.deleteResourceRecords(ctx, ddnsKey, zoneName, recordsToDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range confirmedDeletes(recordsToDelete, deletedRecords) {
+		recordsDeleted = append(recordsDeleted, toLibdnsRR(record, time.Duration(zoneExport.ttl)*time.Second))
+	}
+
 	return recordsDeleted, nil
 }
+
+// expandDeletes converts the records to delete into robot records. A record without a value is replaced
+// by all existing records with the same host and type. A record equal to an existing record in normalized
+// form is sent as stored, so the robot finds it even if the caller wrote the value differently.
+// If matchTTL is set, records with a TTL only match existing records with the same TTL, where records
+// without their own TTL have zoneTTL, and are not sent if nothing matches. If matchValue is set, records
+// without a value match nothing, and records matching no existing value are not sent.
+func expandDeletes(records []libdns.Record, existing []ResourceRecord, zoneName string, zoneTTL int, matchTTL bool, matchValue bool) []ResourceRecord {
+	var recordsToDelete []ResourceRecord
+	for _, record := range records {
+		rr := toResourceRecord(record, zoneName)
+		if matchValue && rr.Value == "" {
+			continue
+		}
+		ttl := int(record.RR().TTL / time.Second)
+		matchTTL := matchTTL && ttl > 0
+		matched := false
+		for _, e := range existing {
+			if !strings.EqualFold(e.Host, rr.Host) || !strings.EqualFold(e.Type, rr.Type) {
+				continue
+			}
+			if matchTTL && ttl != cmp.Or(e.TTL, zoneTTL) {
+				continue
+			}
+			if rr.Value == "" || sameRecord(e, rr) {
+				recordsToDelete = append(recordsToDelete, ResourceRecord{Host: e.Host, Type: e.Type, Value: e.Value, Class: e.Class})
+				matched = true
+			}
+		}
+		if !matched && rr.Value != "" && !matchTTL && !matchValue {
+			rr.TTL = 0
+			recordsToDelete = append(recordsToDelete, rr)
+		}
+	}
+	return recordsToDelete
+}
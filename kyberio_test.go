@@ -0,0 +1,953 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestGetRecordsFailureStatus(t *testing.T) {
+	missingStatus := robottest.Exchange{
+		Action:   actionGetZone,
+		Response: "<html><body>Bad Gateway</body></html>",
+	}
+	for _, test := range []struct {
+		name     string
+		exchange robottest.Exchange
+		want     error
+	}{
+		{"denied", fixture(t, "getzone-denied"), ErrAuthFailed},
+		{"missing status", missingStatus, ErrMissingStatus},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, _ := newTestProvider(t, test.exchange)
+
+			records, err := p.GetRecords(context.Background(), testZone)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("GetRecords() error = %v, want %v", err, test.want)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Action != actionGetZone {
+				t.Errorf("GetRecords() error = %#v, want *APIError for %s", err, actionGetZone)
+			}
+			if len(records) != 0 {
+				t.Errorf("GetRecords() returned %d records along with the error", len(records))
+			}
+		})
+	}
+}
+
+func TestGetRecords(t *testing.T) {
+	p, _ := newTestProvider(t, fixture(t, "getzone"))
+
+	records, err := p.GetRecords(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if len(records) != 5 {
+		t.Errorf("GetRecords() returned %d records, want 5", len(records))
+	}
+}
+
+func TestWritesAgainstDeniedZone(t *testing.T) {
+	ctx := context.Background()
+	p, server := newTestProvider(t, fixture(t, "getzone-denied"), fixture(t, "addorupdaterr"), fixture(t, "delrr"))
+
+	if _, err := p.SetRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}}); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("SetRecords() error = %v, want %v", err, ErrAuthFailed)
+	}
+	// a delete without value has to look up the values to delete
+	if _, err := p.DeleteRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A"}}); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("DeleteRecords() error = %v, want %v", err, ErrAuthFailed)
+	}
+	if _, err := p.GetSOA(ctx, testZone); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("GetSOA() error = %v, want %v", err, ErrAuthFailed)
+	}
+	for _, action := range actions(server) {
+		if action != actionGetZone {
+			t.Errorf("sent %s for a zone the key may not read", action)
+		}
+	}
+}
+
+func TestAddOrUpdateResourceRecordsMixedKeepExisting(t *testing.T) {
+	p, server := newTestProvider(t, robottest.Exchange{
+		Action: actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok" zone="example.com">` +
+			`<rr host="_acme-challenge" type="TXT" value="second" performedAction="added"></rr>` +
+			`<rr host="www" type="A" value="192.0.2.2" performedAction="updated"></rr>` +
+			`</zoneRequest>`,
+	})
+
+	written, err := p.AddOrUpdateResourceRecords(context.Background(), testZone, []ResourceRecord{
+		{Host: "_acme-challenge", Type: "TXT", Value: "second", KeepExisting: true},
+		{Host: "www", Type: "A", Value: "192.0.2.2"},
+	})
+	if err != nil {
+		t.Fatalf("AddOrUpdateResourceRecords() error = %v", err)
+	}
+	if len(written) != 2 || written[0].PerformedAction != "added" || written[1].PerformedAction != "updated" {
+		t.Errorf("AddOrUpdateResourceRecords() = %+v", written)
+	}
+
+	requests := sentRequests(t, server, actionAddOrUpdateRR)
+	if len(requests) != 1 {
+		t.Fatalf("sent %d ADDORUPDATERR requests, want a single one", len(requests))
+	}
+	keep := make(map[string]bool)
+	for _, record := range requests[0].Records {
+		keep[record.Type] = record.KeepExisting
+	}
+	if len(keep) != 2 || !keep["TXT"] || keep["A"] {
+		t.Errorf("sent keepExisting %v, want true for TXT only", keep)
+	}
+}
+
+// afterAction is an http.RoundTripper calling hook once the response to the first request for action has
+// been received in full.
+type afterAction struct {
+	transport http.RoundTripper
+	action    string
+	hook      func()
+	once      sync.Once
+}
+
+// RoundTrip implements http.RoundTripper.
+func (a *afterAction) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	response, err := a.transport.RoundTrip(request)
+	if err != nil || robottest.Action(body) != a.action {
+		return response, err
+	}
+	data, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(data))
+	a.once.Do(a.hook)
+	return response, nil
+}
+
+func TestDeleteRecordsCanceledAfterFirstBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, server := newTestProvider(t,
+		zoneExchange(`<rr host="a" type="A" value="192.0.2.1"></rr><rr host="b" type="A" value="192.0.2.2"></rr>`+
+			`<rr host="c" type="A" value="192.0.2.3"></rr><rr host="d" type="A" value="192.0.2.4"></rr>`),
+		robottest.Exchange{Action: actionDeleteRR, Response: `<zoneRequest status="ok">` +
+			`<rr host="a" type="A" value="192.0.2.1" performedAction="deleted"></rr>` +
+			`<rr host="b" type="A" value="192.0.2.2" performedAction="deleted"></rr></zoneRequest>`},
+	)
+	p.DeleteBatchSize = 2
+	p.HTTPClient = &http.Client{Transport: &afterAction{transport: http.DefaultTransport, action: actionDeleteRR, hook: cancel}}
+
+	deleted, err := p.DeleteRecords(ctx, testZone, []libdns.Record{
+		libdns.RR{Name: "a", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "b", Type: "A", Data: "192.0.2.2"},
+		libdns.RR{Name: "c", Type: "A", Data: "192.0.2.3"},
+		libdns.RR{Name: "d", Type: "A", Data: "192.0.2.4"},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("DeleteRecords() error = %v, want %v", err, context.Canceled)
+	}
+	if len(deleted) != 2 || deleted[0].RR().Name != "a" || deleted[1].RR().Name != "b" {
+		t.Errorf("DeleteRecords() = %v, want the records of the first batch", deleted)
+	}
+	if got := count(actions(server), actionDeleteRR); got != 1 {
+		t.Errorf("sent %d DELRR requests, want 1", got)
+	}
+}
+
+// lowTTLZone answers GETZONE with an empty export of example.com with a zone TTL of 30 seconds.
+var lowTTLZone = robottest.Exchange{
+	Action:   actionGetZone,
+	Response: `<zoneRequest status="ok"><zone name="example.com"><soa mttl="30"></soa></zone></zoneRequest>`,
+}
+
+func TestMinTTL(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		zone    robottest.Exchange
+		ttl     time.Duration
+		wantTTL int
+	}{
+		{"requested TTL below the floor", fixture(t, "getzone"), 30 * time.Second, 60},
+		{"requested TTL above the floor", fixture(t, "getzone"), 120 * time.Second, 120},
+		{"zone TTL above the floor", fixture(t, "getzone"), 0, 0},
+		{"zone TTL below the floor", lowTTLZone, 0, 60},
+	} {
+		for method, call := range map[string]func(ctx context.Context, p *Provider, records []libdns.Record) ([]libdns.Record, error){
+			"AppendRecords": func(ctx context.Context, p *Provider, records []libdns.Record) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, testZone, records)
+			},
+			"SetRecords": func(ctx context.Context, p *Provider, records []libdns.Record) ([]libdns.Record, error) {
+				return p.SetRecords(ctx, testZone, records)
+			},
+		} {
+			t.Run(method+"/"+test.name, func(t *testing.T) {
+				p, server := newTestProvider(t, test.zone, robottest.Exchange{
+					Action:   actionAddOrUpdateRR,
+					Response: `<zoneRequest status="ok"><rr host="new" type="A" value="192.0.2.7" performedAction="added"></rr></zoneRequest>`,
+				})
+				p.MinTTL = time.Minute
+
+				if _, err := call(context.Background(), p, []libdns.Record{libdns.RR{Name: "new", Type: "A", Data: "192.0.2.7", TTL: test.ttl}}); err != nil {
+					t.Fatalf("%s() error = %v", method, err)
+				}
+				if got, want := actions(server), []string{actionGetZone, actionAddOrUpdateRR}; !slices.Equal(got, want) {
+					t.Errorf("%s() sent %v, want %v", method, got, want)
+				}
+				writes := sentRequests(t, server, actionAddOrUpdateRR)
+				if len(writes) != 1 || writes[0].Records[0].TTL != test.wantTTL {
+					t.Errorf("sent ADDORUPDATERR %+v, want ttl %d", writes, test.wantTTL)
+				}
+			})
+		}
+	}
+}
+
+func TestMinTTLLowLevelWrite(t *testing.T) {
+	p, server := newTestProvider(t, lowTTLZone, fixture(t, "addorupdaterr"))
+	p.MinTTL = time.Minute
+
+	if _, err := p.AddOrUpdateResourceRecords(context.Background(), testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.2", KeepExisting: true}}); err != nil {
+		t.Fatalf("AddOrUpdateResourceRecords() error = %v", err)
+	}
+	if got, want := actions(server), []string{actionGetZone, actionAddOrUpdateRR}; !slices.Equal(got, want) {
+		t.Errorf("AddOrUpdateResourceRecords() sent %v, want %v", got, want)
+	}
+	writes := sentRequests(t, server, actionAddOrUpdateRR)
+	if len(writes) != 1 || writes[0].Records[0].TTL != 60 {
+		t.Errorf("sent ADDORUPDATERR %+v, want ttl 60 for the zone TTL of 30", writes)
+	}
+}
+
+// sentDeletes returns the values of the records of all DELRR requests the server received, in order.
+func sentDeletes(t *testing.T, server *robottest.Server) []string {
+	t.Helper()
+	var result []string
+	for _, request := range sentRequests(t, server, actionDeleteRR) {
+		result = append(result, values(request.Records)...)
+	}
+	return result
+}
+
+// deletedExchange answers DELRR with status ok and no records.
+var deletedExchange = robottest.Exchange{Action: actionDeleteRR, Response: `<zoneRequest status="ok"></zoneRequest>`}
+
+func TestMatchValueOnDelete(t *testing.T) {
+	challenges := zoneExchange(`<rr host="_acme-challenge" type="TXT" value="a"></rr><rr host="_acme-challenge" type="TXT" value="b"></rr>`)
+	for _, test := range []struct {
+		name       string
+		value      string
+		matchValue bool
+		want       []string
+	}{
+		{"empty value deletes the RRset", "", false, []string{"a", "b"}},
+		{"empty value matches nothing", "", true, nil},
+		{"unknown value is sent", "c", false, []string{"c"}},
+		{"unknown value is skipped", "c", true, nil},
+		{"matching value", "a", true, []string{"a"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, challenges, deletedExchange)
+			p.MatchValueOnDelete = test.matchValue
+
+			if _, err := p.DeleteRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "_acme-challenge", Type: "TXT", Data: test.value}}); err != nil {
+				t.Fatalf("DeleteRecords() error = %v", err)
+			}
+			if got := sentDeletes(t, server); !slices.Equal(got, test.want) {
+				t.Errorf("sent DELRR for %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchTTLOnDelete(t *testing.T) {
+	// the zone TTL of zoneExchange is 3600
+	www := zoneExchange(`<rr host="www" type="A" value="192.0.2.1" ttl="300"></rr><rr host="www" type="A" value="192.0.2.2"></rr>`)
+	for _, test := range []struct {
+		name     string
+		record   libdns.RR
+		matchTTL bool
+		want     []string
+	}{
+		{"RRset without matching", libdns.RR{Name: "www", Type: "A", TTL: 300 * time.Second}, false, []string{"192.0.2.1", "192.0.2.2"}},
+		{"RRset by own TTL", libdns.RR{Name: "www", Type: "A", TTL: 300 * time.Second}, true, []string{"192.0.2.1"}},
+		{"RRset by zone TTL", libdns.RR{Name: "www", Type: "A", TTL: time.Hour}, true, []string{"192.0.2.2"}},
+		{"value with other TTL", libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2", TTL: 300 * time.Second}, true, nil},
+		{"value without TTL", libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"}, true, []string{"192.0.2.1"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, www, deletedExchange)
+			p.MatchTTLOnDelete = test.matchTTL
+
+			if _, err := p.DeleteRecords(context.Background(), testZone, []libdns.Record{test.record}); err != nil {
+				t.Fatalf("DeleteRecords() error = %v", err)
+			}
+			if got := sentDeletes(t, server); !slices.Equal(got, test.want) {
+				t.Errorf("sent DELRR for %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMaxDeletes(t *testing.T) {
+	three := zoneExchange(`<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.2"></rr><rr host="www" type="A" value="192.0.2.3"></rr>`)
+
+	t.Run("exceeded", func(t *testing.T) {
+		p, server := newTestProvider(t, three, deletedExchange)
+		p.MaxDeletes = 1
+
+		if _, err := p.SetRecords(context.Background(), testZone, aRecords("192.0.2.1")); !errors.Is(err, ErrTooManyDeletes) {
+			t.Fatalf("SetRecords() error = %v, want %v", err, ErrTooManyDeletes)
+		}
+		if got := actions(server); !slices.Equal(got, []string{actionGetZone}) {
+			t.Errorf("sent %v, want nothing written after %s", got, actionGetZone)
+		}
+	})
+
+	t.Run("within the limit", func(t *testing.T) {
+		p, server := newTestProvider(t, three, deletedExchange)
+		p.MaxDeletes = 2
+
+		if _, err := p.SetRecords(context.Background(), testZone, aRecords("192.0.2.1")); err != nil {
+			t.Fatalf("SetRecords() error = %v", err)
+		}
+		if got, want := sentDeletes(t, server), []string{"192.0.2.2", "192.0.2.3"}; !slices.Equal(got, want) {
+			t.Errorf("sent DELRR for %q, want %q", got, want)
+		}
+	})
+}
+
+func TestCreateRecords(t *testing.T) {
+	created := robottest.Exchange{
+		Action:   actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok"><rr host="new" type="A" value="192.0.2.7" performedAction="added"></rr></zoneRequest>`,
+	}
+
+	t.Run("new record", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), created)
+
+		records, err := p.CreateRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "new", Type: "A", Data: "192.0.2.7"}})
+		if err != nil {
+			t.Fatalf("CreateRecords() error = %v", err)
+		}
+		if len(records) != 1 || records[0].RR().Name != "new" || records[0].RR().Data != "192.0.2.7" {
+			t.Errorf("CreateRecords() = %v, want the new record", records)
+		}
+		writes := sentRequests(t, server, actionAddOrUpdateRR)
+		if len(writes) != 1 || len(writes[0].Records) != 1 || !writes[0].Records[0].KeepExisting {
+			t.Errorf("sent ADDORUPDATERR %+v, want the new record with keepExisting", writes)
+		}
+	})
+
+	t.Run("existing record", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), created)
+
+		records, err := p.CreateRecords(context.Background(), testZone, []libdns.Record{
+			libdns.RR{Name: "new", Type: "A", Data: "192.0.2.7"},
+			libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		})
+		if !errors.Is(err, ErrRecordExists) {
+			t.Fatalf("CreateRecords() error = %v, want %v", err, ErrRecordExists)
+		}
+		if !strings.Contains(err.Error(), `www A "192.0.2.1"`) {
+			t.Errorf("CreateRecords() error = %v, want it to name the existing www record", err)
+		}
+		if len(records) != 0 {
+			t.Errorf("CreateRecords() = %v, want nothing created", records)
+		}
+		if got := actions(server); !slices.Equal(got, []string{actionGetZone}) {
+			t.Errorf("sent %v, want nothing written after %s", got, actionGetZone)
+		}
+	})
+
+	t.Run("created concurrently", func(t *testing.T) {
+		p, _ := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+			Action: actionAddOrUpdateRR,
+			Response: `<zoneRequest status="ok"><rr host="new" type="A" value="192.0.2.7" performedAction="added"></rr>` +
+				`<rr host="other" type="A" value="192.0.2.8" performedAction="none"></rr></zoneRequest>`,
+		})
+
+		records, err := p.CreateRecords(context.Background(), testZone, []libdns.Record{
+			libdns.RR{Name: "new", Type: "A", Data: "192.0.2.7"},
+			libdns.RR{Name: "other", Type: "A", Data: "192.0.2.8"},
+		})
+		if !errors.Is(err, ErrRecordExists) {
+			t.Fatalf("CreateRecords() error = %v, want %v", err, ErrRecordExists)
+		}
+		if len(records) != 1 || records[0].RR().Name != "new" {
+			t.Errorf("CreateRecords() = %v, want the record that was added", records)
+		}
+	})
+}
+
+func TestDeduplicateRecords(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		records string
+		want    int
+	}{
+		{"identical records", `<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.1"></rr>`, 1},
+		{"differently written address", `<rr host="www" type="AAAA" value="2001:db8::1"></rr><rr host="www" type="AAAA" value="2001:0DB8:0:0:0:0:0:1"></rr>`, 1},
+		{"differently cased type", `<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="a" value="192.0.2.1"></rr>`, 1},
+		{"zone TTL given explicitly", `<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.1" ttl="3600"></rr>`, 1},
+		{"other TTL", `<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.1" ttl="300"></rr>`, 2},
+		{"other value", `<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.2"></rr>`, 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, deduplicate := range []bool{false, true} {
+				p, _ := newTestProvider(t, zoneExchange(test.records))
+				p.DeduplicateRecords = deduplicate
+
+				records, err := p.GetRecords(context.Background(), testZone)
+				if err != nil {
+					t.Fatalf("GetRecords() error = %v", err)
+				}
+				want := 2
+				if deduplicate {
+					want = test.want
+				}
+				if len(records) != want {
+					t.Errorf("GetRecords() with DeduplicateRecords %t = %v, want %d records", deduplicate, records, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAppendRecordsExisting(t *testing.T) {
+	added := robottest.Exchange{
+		Action:   actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok"><rr host="new" type="A" value="192.0.2.7" performedAction="added"></rr></zoneRequest>`,
+	}
+
+	t.Run("only existing records", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), added)
+
+		records, err := p.AppendRecords(context.Background(), testZone, aRecords("192.0.2.1"))
+		if err != nil {
+			t.Fatalf("AppendRecords() error = %v", err)
+		}
+		if len(records) != 1 || records[0].RR().Data != "192.0.2.1" {
+			t.Errorf("AppendRecords() = %v, want the existing record", records)
+		}
+		if got := actions(server); !slices.Equal(got, []string{actionGetZone}) {
+			t.Errorf("sent %v, want only %s for a record the zone holds already", got, actionGetZone)
+		}
+	})
+
+	t.Run("existing and new records", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), added)
+
+		results, err := p.AppendRecordsWithResults(context.Background(), testZone, []libdns.Record{
+			libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+			libdns.RR{Name: "new", Type: "A", Data: "192.0.2.7"},
+		})
+		if err != nil {
+			t.Fatalf("AppendRecordsWithResults() error = %v", err)
+		}
+		actions := make(map[string]string)
+		for _, result := range results {
+			actions[result.Record.RR().Name] = result.Action
+		}
+		if len(results) != 2 || actions["www"] != ActionExisting || actions["new"] != ActionAdded {
+			t.Errorf("AppendRecordsWithResults() = %+v, want www existing and new added", results)
+		}
+		writes := sentRequests(t, server, actionAddOrUpdateRR)
+		if len(writes) != 1 || len(writes[0].Records) != 1 || writes[0].Records[0].Host != "new" {
+			t.Errorf("sent ADDORUPDATERR %+v, want only the new record", writes)
+		}
+	})
+}
+
+func TestAppendRecordsExistingTTL(t *testing.T) {
+	// the zone TTL of zoneExchange is 3600
+	p, server := newTestProvider(t, zoneExchange(`<rr host="www" type="A" value="192.0.2.1" ttl="300"></rr>`), fixture(t, "addorupdaterr"))
+
+	results, err := p.AppendRecordsWithResults(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: 600 * time.Second}})
+	if err != nil {
+		t.Fatalf("AppendRecordsWithResults() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Action != ActionExisting || results[0].Record.RR().TTL != 300*time.Second {
+		t.Errorf("AppendRecordsWithResults() = %+v, want the existing record with its TTL of 5m", results)
+	}
+	if got := count(actions(server), actionAddOrUpdateRR); got != 0 {
+		t.Errorf("sent %d ADDORUPDATERR requests, want none for an existing record", got)
+	}
+}
+
+func TestIncompleteWriteResponse(t *testing.T) {
+	// the robot reports only the first of the two records
+	short := robottest.Exchange{
+		Action:   actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok"><rr host="a" type="A" value="192.0.2.1" performedAction="added"></rr></zoneRequest>`,
+	}
+	records := []libdns.Record{
+		libdns.RR{Name: "a", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "b", Type: "A", Data: "192.0.2.2"},
+	}
+	for method, call := range map[string]func(ctx context.Context, p *Provider) error{
+		"AddOrUpdateResourceRecords": func(ctx context.Context, p *Provider) error {
+			_, err := p.AddOrUpdateResourceRecords(ctx, testZone, []ResourceRecord{
+				{Host: "a", Type: "A", Value: "192.0.2.1"},
+				{Host: "b", Type: "A", Value: "192.0.2.2"},
+			})
+			return err
+		},
+		"AppendRecords": func(ctx context.Context, p *Provider) error {
+			_, err := p.AppendRecords(ctx, testZone, records)
+			return err
+		},
+		"SetRecords": func(ctx context.Context, p *Provider) error {
+			_, err := p.SetRecords(ctx, testZone, records)
+			return err
+		},
+	} {
+		t.Run(method, func(t *testing.T) {
+			p, _ := newTestProvider(t, zoneExchange(""), short)
+
+			err := call(context.Background(), p)
+			if !errors.Is(err, ErrIncompleteResponse) {
+				t.Fatalf("%s() error = %v, want %v", method, err, ErrIncompleteResponse)
+			}
+			var incomplete *IncompleteResponseError
+			if !errors.As(err, &incomplete) || len(incomplete.Missing) != 1 || incomplete.Missing[0].Host != "b" {
+				t.Errorf("%s() error = %#v, want *IncompleteResponseError naming b", method, err)
+			}
+		})
+	}
+}
+
+func TestWriteMissingStatus(t *testing.T) {
+	const body = "<html><body>Bad Gateway</body></html>"
+	for _, test := range []struct {
+		action string
+		call   func(ctx context.Context, p *Provider) ([]libdns.Record, error)
+	}{
+		{actionAddOrUpdateRR, func(ctx context.Context, p *Provider) ([]libdns.Record, error) {
+			return p.AppendRecords(ctx, testZone, aRecords("192.0.2.2"))
+		}},
+		{actionDeleteRR, func(ctx context.Context, p *Provider) ([]libdns.Record, error) {
+			return p.DeleteRecords(ctx, testZone, aRecords("192.0.2.1"))
+		}},
+	} {
+		t.Run(test.action, func(t *testing.T) {
+			p, _ := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{Action: test.action, Response: body})
+
+			records, err := test.call(context.Background(), p)
+			if !errors.Is(err, ErrMissingStatus) {
+				t.Fatalf("error = %v, want %v", err, ErrMissingStatus)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Action != test.action || apiErr.Body != body {
+				t.Errorf("error = %#v, want *APIError for %s with the body", err, test.action)
+			}
+			if !strings.Contains(err.Error(), "Bad Gateway") {
+				t.Errorf("error = %v, want it to quote the body", err)
+			}
+			if len(records) != 0 {
+				t.Errorf("returned %v along with the error", records)
+			}
+		})
+	}
+}
+
+func TestGetRecordsServerOrder(t *testing.T) {
+	zone := zoneExchange(`<rr host="@" type="MX" value="20 backup.example.com."></rr>` +
+		`<rr host="www" type="A" value="192.0.2.2"></rr>` +
+		`<rr host="@" type="MX" value="10 mail.example.com."></rr>` +
+		`<rr host="www" type="A" value="192.0.2.2"></rr>` +
+		`<rr host="a" type="A" value="192.0.2.1"></rr>`)
+	for _, test := range []struct {
+		deduplicate bool
+		want        []string
+	}{
+		{false, []string{"20 backup.example.com.", "192.0.2.2", "10 mail.example.com.", "192.0.2.2", "192.0.2.1"}},
+		{true, []string{"20 backup.example.com.", "192.0.2.2", "10 mail.example.com.", "192.0.2.1"}},
+	} {
+		p, _ := newTestProvider(t, zone)
+		p.DeduplicateRecords = test.deduplicate
+
+		records, err := p.GetRecords(context.Background(), testZone)
+		if err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+		var got []string
+		for _, record := range records {
+			got = append(got, record.RR().Data)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("GetRecords() with DeduplicateRecords %t = %q, want the order of the response %q", test.deduplicate, got, test.want)
+		}
+	}
+}
+
+func TestClampTTL(t *testing.T) {
+	for _, test := range []struct{ seconds, want int }{
+		{-1, 0},
+		{math.MinInt, 0},
+		{0, 0},
+		{1, 1},
+		{math.MaxInt32, math.MaxInt32},
+		{math.MaxInt32 + 1, math.MaxInt32},
+		{math.MaxInt, math.MaxInt32},
+	} {
+		if got := clampTTL(test.seconds); got != test.want {
+			t.Errorf("clampTTL(%d) = %d, want %d", test.seconds, got, test.want)
+		}
+	}
+}
+
+func TestTTLRangeOnRead(t *testing.T) {
+	p, _ := newTestProvider(t, robottest.Exchange{
+		Action: actionGetZone,
+		Response: `<zoneRequest status="ok"><zone name="example.com"><soa mttl="99999999999"></soa>` +
+			`<rr host="www" type="A" value="192.0.2.1"></rr>` +
+			`<rr host="www" type="A" value="192.0.2.2" ttl="-5"></rr>` +
+			`<rr host="www" type="A" value="192.0.2.3" ttl="99999999999"></rr>` +
+			`</zone></zoneRequest>`,
+	})
+
+	records, err := p.GetRecords(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	for _, record := range records {
+		if got, want := record.RR().TTL, time.Duration(math.MaxInt32)*time.Second; got != want {
+			t.Errorf("GetRecords() record %v with TTL %v, want %v", record, got, want)
+		}
+	}
+}
+
+func TestTTLRangeOnWrite(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		ttl     time.Duration
+		wantTTL int
+	}{
+		{"negative", -time.Second, 0},
+		{"largest", math.MaxInt32 * time.Second, math.MaxInt32},
+		{"beyond the largest", (math.MaxInt32 + 1) * time.Second, math.MaxInt32},
+		{"100 years", 100 * 365 * 24 * time.Hour, math.MaxInt32},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, zoneExchange(""), robottest.Exchange{
+				Action:   actionAddOrUpdateRR,
+				Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.1" performedAction="added"></rr></zoneRequest>`,
+			})
+
+			if _, err := p.AppendRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: test.ttl}}); err != nil {
+				t.Fatalf("AppendRecords() error = %v", err)
+			}
+			if _, err := p.AddOrUpdateResourceRecords(context.Background(), testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.1", TTL: int(test.ttl / time.Second)}}); err != nil {
+				t.Fatalf("AddOrUpdateResourceRecords() error = %v", err)
+			}
+			writes := sentRequests(t, server, actionAddOrUpdateRR)
+			if len(writes) != 2 {
+				t.Fatalf("sent %d ADDORUPDATERR requests, want 2", len(writes))
+			}
+			for _, write := range writes {
+				if got := write.Records[0].TTL; got != test.wantTTL {
+					t.Errorf("sent ttl %d, want %d", got, test.wantTTL)
+				}
+			}
+		})
+	}
+}
+
+func TestZeroZoneTTL(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		mttl          string
+		defaultTTL    time.Duration
+		rejectZeroTTL bool
+		wantTTL       time.Duration
+		wantErr       error
+		wantWarning   bool
+	}{
+		{"warning", "0", 0, false, 0, nil, true},
+		{"negative MTTL", "-30", 0, false, 0, nil, true},
+		{"DefaultTTL", "0", 5 * time.Minute, false, 5 * time.Minute, nil, false},
+		{"DefaultTTL before RejectZeroTTL", "0", 5 * time.Minute, true, 5 * time.Minute, nil, false},
+		{"RejectZeroTTL", "0", 0, true, 0, ErrZeroTTL, false},
+		{"nonzero MTTL", "3600", 5 * time.Minute, true, time.Hour, nil, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var logged bytes.Buffer
+			p, _ := newTestProvider(t, robottest.Exchange{
+				Action:   actionGetZone,
+				Response: `<zoneRequest status="ok"><zone name="example.com"><soa mttl="` + test.mttl + `"></soa><rr host="www" type="A" value="192.0.2.1"></rr></zone></zoneRequest>`,
+			})
+			p.Logger = slog.New(slog.NewTextHandler(&logged, nil))
+			p.DefaultTTL = test.defaultTTL
+			p.RejectZeroTTL = test.rejectZeroTTL
+
+			records, err := p.GetRecords(context.Background(), testZone)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("GetRecords() error = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && (len(records) != 1 || records[0].RR().TTL != test.wantTTL) {
+				t.Errorf("GetRecords() = %v, want TTL %v", records, test.wantTTL)
+			}
+			if warned := strings.Contains(logged.String(), "level=WARN"); warned != test.wantWarning {
+				t.Errorf("logged %q, want a warning: %t", logged.String(), test.wantWarning)
+			}
+		})
+	}
+}
+
+func TestGetRecordsByType(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		zone robottest.Exchange
+	}{
+		{"filtered by the robot", zoneExchange(`<rr host="_acme-challenge" type="TXT" value="token"></rr>`)},
+		{"filter ignored by the robot", fixture(t, "getzone")},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, test.zone)
+
+			records, err := p.GetRecordsByType(context.Background(), testZone, "txt")
+			if err != nil {
+				t.Fatalf("GetRecordsByType() error = %v", err)
+			}
+			if len(records) != 1 || records[0].RR().Type != "TXT" || records[0].RR().Data != "token" {
+				t.Errorf("GetRecordsByType() = %v, want the TXT record only", records)
+			}
+			if requests := sentRequests(t, server, actionGetZone); len(requests) != 1 || !strings.EqualFold(requests[0].Type, "TXT") {
+				t.Errorf("sent GETZONE %+v, want a single one for type TXT", requests)
+			}
+		})
+	}
+
+	t.Run("cached zone", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"))
+		p.ZoneCacheTTL = time.Minute
+
+		if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+		records, err := p.GetRecordsByType(context.Background(), testZone, "A")
+		if err != nil {
+			t.Fatalf("GetRecordsByType() error = %v", err)
+		}
+		if len(records) != 1 || records[0].RR().Data != "192.0.2.1" {
+			t.Errorf("GetRecordsByType() = %v, want the A record only", records)
+		}
+		if got := count(actions(server), actionGetZone); got != 1 {
+			t.Errorf("sent %d GETZONE requests, want the cached zone to be filtered", got)
+		}
+	})
+}
+
+func TestDeleteName(t *testing.T) {
+	zone := zoneExchange(`<rr host="old" type="A" value="192.0.2.1"></rr><rr host="old" type="AAAA" value="2001:db8::1"></rr>` +
+		`<rr host="www" type="A" value="192.0.2.2"></rr><rr host="OLD" type="TXT" value="decommissioned"></rr>` +
+		`<rr host="sub.old" type="A" value="192.0.2.3"></rr>`)
+
+	t.Run("A, AAAA and TXT records", func(t *testing.T) {
+		p, server := newTestProvider(t, zone, robottest.Exchange{
+			Action: actionDeleteRR,
+			Response: `<zoneRequest status="ok">` +
+				`<rr host="old" type="A" value="192.0.2.1" performedAction="deleted"></rr>` +
+				`<rr host="old" type="AAAA" value="2001:db8::1" performedAction="deleted"></rr>` +
+				`<rr host="OLD" type="TXT" value="decommissioned" performedAction="deleted"></rr>` +
+				`</zoneRequest>`,
+		})
+
+		deleted, err := p.DeleteName(context.Background(), testZone, "old.example.com.")
+		if err != nil {
+			t.Fatalf("DeleteName() error = %v", err)
+		}
+		var types []string
+		for _, record := range deleted {
+			types = append(types, record.RR().Type)
+		}
+		if want := []string{"A", "AAAA", "TXT"}; !slices.Equal(types, want) {
+			t.Errorf("DeleteName() deleted %v, want the records of types %v", deleted, want)
+		}
+		deletes := sentRequests(t, server, actionDeleteRR)
+		if len(deletes) != 1 || len(deletes[0].Records) != 3 {
+			t.Fatalf("sent DELRR %+v, want a single one for the three records of old", deletes)
+		}
+		for _, record := range deletes[0].Records {
+			if !strings.EqualFold(record.Host, "old") {
+				t.Errorf("sent DELRR for %s %s, want only records of old", record.Host, record.Type)
+			}
+		}
+	})
+
+	t.Run("name without records", func(t *testing.T) {
+		p, server := newTestProvider(t, zone, deletedExchange)
+
+		deleted, err := p.DeleteName(context.Background(), testZone, "new")
+		if err != nil || len(deleted) != 0 {
+			t.Fatalf("DeleteName() = %v, %v, want nothing deleted", deleted, err)
+		}
+		if got := count(actions(server), actionDeleteRR); got != 0 {
+			t.Errorf("sent %d DELRR requests, want none", got)
+		}
+	})
+}
+
+func TestDeleteRecordsCorrelatesInput(t *testing.T) {
+	// the robot echoes the records in another order and form, and reports a record that was not asked for
+	p, _ := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+		Action: actionDeleteRR,
+		Response: `<zoneRequest status="ok">` +
+			`<rr host="_acme-challenge.example.com." type="TXT" value="token" performedAction="deleted"></rr>` +
+			`<rr host="WWW" type="aaaa" value="2001:db8::1" performedAction="deleted"></rr>` +
+			`<rr host="other" type="A" value="192.0.2.8" performedAction="deleted"></rr>` +
+			`<rr host="www" type="A" value="192.0.2.1" performedAction="deleted"></rr>` +
+			`</zoneRequest>`,
+	})
+
+	deleted, err := p.DeleteRecords(context.Background(), testZone, []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "missing", Type: "A", Data: "192.0.2.9"},
+		libdns.RR{Name: "www", Type: "AAAA", Data: "2001:0DB8:0:0:0:0:0:1"},
+		libdns.RR{Name: "_acme-challenge", Type: "TXT", Data: "token"},
+	})
+	if err != nil {
+		t.Fatalf("DeleteRecords() error = %v", err)
+	}
+	var got []string
+	for _, record := range deleted {
+		got = append(got, record.RR().Name+" "+record.RR().Type+" "+record.RR().Data)
+	}
+	if want := []string{"www A 192.0.2.1", "www AAAA 2001:db8::1", "_acme-challenge TXT token"}; !slices.Equal(got, want) {
+		t.Errorf("DeleteRecords() = %q, want %q", got, want)
+	}
+}
+
+func TestDeleteRecordsWithResults(t *testing.T) {
+	p, _ := newTestProvider(t, zoneExchange(
+		`<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.2"></rr><rr host="locked" type="A" value="192.0.2.3"></rr>`,
+	), robottest.Exchange{
+		Action: actionDeleteRR,
+		Response: `<zoneRequest status="ok">` +
+			`<rr host="www" type="A" value="192.0.2.1" performedAction="deleted"></rr>` +
+			`<rr host="locked" type="A" value="192.0.2.3" performedAction="error" warning="record is locked"></rr>` +
+			`</zoneRequest>`,
+	})
+	p.MatchValueOnDelete = true
+
+	input := []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "missing", Type: "A", Data: "192.0.2.9"},
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"},
+		libdns.RR{Name: "locked", Type: "A", Data: "192.0.2.3"},
+	}
+	results, err := p.DeleteRecordsWithResults(context.Background(), testZone, input)
+	if err != nil {
+		t.Fatalf("DeleteRecordsWithResults() error = %v", err)
+	}
+	want := []string{ActionDeleted, ActionNotFound, ActionNotFound, "error"}
+	if len(results) != len(want) {
+		t.Fatalf("DeleteRecordsWithResults() = %+v, want a result per input record", results)
+	}
+	for i, result := range results {
+		if result.Action != want[i] || result.Record.Data != input[i].RR().Data {
+			t.Errorf("result %d = %+v, want %s for %v", i, result, want[i], input[i])
+		}
+	}
+	if results[3].Warning != "record is locked" {
+		t.Errorf("result of the locked record = %+v, want the warning of the robot", results[3])
+	}
+
+	deleted, err := p.DeleteRecords(context.Background(), testZone, input)
+	if err != nil {
+		t.Fatalf("DeleteRecords() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].RR().Data != "192.0.2.1" {
+		t.Errorf("DeleteRecords() = %v, want only the deleted record", deleted)
+	}
+}
+
+func TestEmptyZone(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetRecords", func(t *testing.T) {
+		p, _ := newTestProvider(t, fixture(t, "getzone-empty"))
+
+		records, err := p.GetRecords(ctx, testZone)
+		if err != nil || len(records) != 0 {
+			t.Errorf("GetRecords() = %v, %v, want no records", records, err)
+		}
+	})
+
+	t.Run("SetRecords", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone-empty"), robottest.Exchange{
+			Action:   actionAddOrUpdateRR,
+			Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.1" performedAction="added"></rr></zoneRequest>`,
+		}, deletedExchange)
+
+		results, err := p.SetRecordsWithResults(ctx, testZone, aRecords("192.0.2.1"))
+		if err != nil {
+			t.Fatalf("SetRecordsWithResults() error = %v", err)
+		}
+		if len(results) != 1 || results[0].Action != ActionAdded {
+			t.Errorf("SetRecordsWithResults() = %+v, want the record added", results)
+		}
+		if got := count(actions(server), actionDeleteRR); got != 0 {
+			t.Errorf("sent %d DELRR requests, want none", got)
+		}
+	})
+
+	t.Run("DeleteRecords", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone-empty"), deletedExchange)
+		p.MatchValueOnDelete = true
+
+		results, err := p.DeleteRecordsWithResults(ctx, testZone, []libdns.Record{
+			libdns.RR{Name: "www", Type: "A"},
+			libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		})
+		if err != nil {
+			t.Fatalf("DeleteRecordsWithResults() error = %v", err)
+		}
+		if len(results) != 2 || results[0].Action != ActionNotFound || results[1].Action != ActionNotFound {
+			t.Errorf("DeleteRecordsWithResults() = %+v, want both not found", results)
+		}
+		if got := actions(server); !slices.Equal(got, []string{actionGetZone}) {
+			t.Errorf("sent %v, want nothing after %s", got, actionGetZone)
+		}
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone-empty"), fixture(t, "addorupdaterr"), deletedExchange)
+
+		for method, call := range map[string]func() ([]libdns.Record, error){
+			"AppendRecords": func() ([]libdns.Record, error) { return p.AppendRecords(ctx, testZone, nil) },
+			"SetRecords":    func() ([]libdns.Record, error) { return p.SetRecords(ctx, testZone, nil) },
+			"DeleteRecords": func() ([]libdns.Record, error) { return p.DeleteRecords(ctx, testZone, nil) },
+		} {
+			if records, err := call(); err != nil || len(records) != 0 {
+				t.Errorf("%s() = %v, %v, want nothing", method, records, err)
+			}
+		}
+		for _, action := range actions(server) {
+			if action != actionGetZone {
+				t.Errorf("sent %s for an empty input", action)
+			}
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// defaultMaxConcurrency bounds the number of requests a fan-out operation keeps in flight unless
+// MaxConcurrency is set.
+const defaultMaxConcurrency = 4
+
+// maxConcurrency returns the number of requests a fan-out operation may keep in flight.
+func (p *Provider) maxConcurrency() int {
+	if p.MaxConcurrency > 0 {
+		return p.MaxConcurrency
+	}
+	return defaultMaxConcurrency
+}
+
+// addOrUpdateZones sends one ADDORUPDATERR request per zone in changes, running at most
+// MaxConcurrency requests at a time. It returns the robot's records of every zone that
+// succeeded, and the errors of all failed zones joined together.
+func (p *Provider) addOrUpdateZones(ctx context.Context, ddnsKey string, changes map[string][]libdns.Record, keepExisting bool) (map[string][]ResourceRecord, error) {
+	zones := make([]string, 0, len(changes))
+	for zone := range changes {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	var mu sync.Mutex
+	results := make(map[string][]ResourceRecord, len(zones))
+	errs := fanOut(ctx, p.maxConcurrency(), len(zones), func(ctx context.Context, i int) error {
+		records, err := p.addOrUpdateRR(ctx, ddnsKey, zones[i], changes[zones[i]], keepExisting)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		results[zones[i]] = records
+		mu.Unlock()
+		return nil
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("zone %s: %w", zones[i], err)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// getRecordsMulti fetches the records of every zone, running at most MaxConcurrency requests at a time. It
+// returns the records of every zone that succeeded, and the errors of all failed zones joined together.
+func (p *Provider) getRecordsMulti(ctx context.Context, ddnsKey string, zones []string) (map[string][]libdns.Record, error) {
+	var mu sync.Mutex
+	results := make(map[string][]libdns.Record, len(zones))
+	errs := fanOut(ctx, p.maxConcurrency(), len(zones), func(ctx context.Context, i int) error {
+		view, err := p.resolveZone(ctx, ddnsKey, zones[i])
+		if err != nil {
+			return err
+		}
+		records, err := p.getRecords(ctx, ddnsKey, view.zone)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		results[zones[i]] = p.parseRecords(ctx, p.unicodeNames(p.absoluteNames(view.fromRobot(records), view.callerZone())))
+		mu.Unlock()
+		return nil
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("zone %s: %w", zones[i], err)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// fanOut calls fn for every index in [0, n) with at most limit calls running at once and returns the
+// error of each call by index. Calls that have not been started when ctx is done are skipped and
+// report the context error instead.
+func fanOut(ctx context.Context, limit int, n int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			<-sem
+			errs[i] = err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(ctx, i)
+		}(i)
+	}
+
+	wg.Wait()
+	return errs
+}
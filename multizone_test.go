@@ -0,0 +1,90 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetRecordsMultiConcurrencyLimit(t *testing.T) {
+	export := fixture(t, "getzone").Response
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if n <= seen || maxInFlight.CompareAndSwap(seen, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(export))
+	}))
+	defer server.Close()
+	p := &Provider{APIToken: "test-key", Endpoint: server.URL, MaxConcurrency: 2}
+
+	zones := make([]string, 8)
+	for i := range zones {
+		zones[i] = fmt.Sprintf("zone%d.example.", i)
+	}
+	results, err := p.GetRecordsMulti(context.Background(), zones)
+	if err != nil {
+		t.Fatalf("GetRecordsMulti() error = %v", err)
+	}
+	if len(results) != len(zones) {
+		t.Errorf("GetRecordsMulti() returned %d zones, want %d", len(results), len(zones))
+	}
+	if got := maxInFlight.Load(); got != 2 {
+		t.Errorf("%d requests were in flight at once, want MaxConcurrency 2", got)
+	}
+}
+
+func TestGetRecordsMultiNames(t *testing.T) {
+	exchange := zoneExchange(`<rr host="xn--mnchen-3ya" type="A" value="192.0.2.1"></rr>`)
+	p, _ := newTestProvider(t, exchange)
+	p.AbsoluteNames = true
+	p.UnicodeNames = true
+
+	want, err := p.GetRecords(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	results, err := p.GetRecordsMulti(context.Background(), []string{testZone})
+	if err != nil {
+		t.Fatalf("GetRecordsMulti() error = %v", err)
+	}
+	got := results[testZone]
+	if len(got) != 1 || len(want) != 1 || got[0].RR().Name != want[0].RR().Name {
+		t.Fatalf("GetRecordsMulti() = %v, want the records of GetRecords %v", got, want)
+	}
+	if name := got[0].RR().Name; name != "münchen.example.com." {
+		t.Errorf("GetRecordsMulti() name = %q, want münchen.example.com.", name)
+	}
+}
+
+func TestFanOutCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	errs := fanOut(ctx, 1, 5, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		if i == 1 {
+			cancel()
+		}
+		return nil
+	})
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("fanOut() started %d calls, want 2 before the cancellation", got)
+	}
+	for i, err := range errs {
+		if want := i > 1; errors.Is(err, context.Canceled) != want {
+			t.Errorf("call %d error = %v", i, err)
+		}
+	}
+}
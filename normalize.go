@@ -0,0 +1,164 @@
+package libdns_kyberio
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// apexHost is the host of records at the zone apex.
+const apexHost = "@"
+
+// normalizeValue returns the canonical form of a record value of the given type, so that equivalent
+// values written in different ways compare equal. Values that cannot be parsed are returned unchanged.
+func normalizeValue(rtype string, value string) string {
+	switch strings.ToUpper(rtype) {
+	case "AAAA":
+		// 2001:0DB8:0:0:0:0:0:1 and 2001:db8::1 are the same address
+		if addr, err := netip.ParseAddr(strings.TrimSpace(value)); err == nil && addr.Is6() {
+			return addr.String()
+		}
+	case "CNAME", "NS", "PTR", "DNAME":
+		return normalizeHostname(value)
+	case "MX":
+		// <preference> <exchange>
+		if fields := strings.Fields(value); len(fields) == 2 {
+			return fields[0] + " " + normalizeHostname(fields[1])
+		}
+	case "SRV":
+		// <priority> <weight> <port> <target>
+		if fields := strings.Fields(value); len(fields) == 4 {
+			return strings.Join(fields[:3], " ") + " " + normalizeHostname(fields[3])
+		}
+	case "DS", "DNSKEY":
+		return normalizeKeyData(rtype, value)
+	case "TXT":
+		// "v=spf1 " "-all" and v=spf1 -all are the same text
+		if text, ok := joinCharacterStrings(value); ok {
+			return text
+		}
+	}
+	return value
+}
+
+// joinCharacterStrings returns the text of a TXT value made of quoted character-strings, like
+// "part one" "part two", with the parts concatenated and escapes resolved, as libdns expects it. It
+// reports false if the value is not entirely made of quoted character-strings.
+func joinCharacterStrings(value string) (string, bool) {
+	var b strings.Builder
+	rest := strings.TrimSpace(value)
+	if !strings.HasPrefix(rest, `"`) {
+		return "", false
+	}
+	for rest != "" {
+		if rest[0] != '"' {
+			return "", false
+		}
+		i := 1
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] != '\\' || i+1 >= len(rest) {
+				b.WriteByte(rest[i])
+				continue
+			}
+			// \DDD is a decimal byte, any other escaped character stands for itself
+			if i+3 < len(rest) && isDigits(rest[i+1:i+4]) {
+				n, _ := strconv.Atoi(rest[i+1 : i+4])
+				b.WriteByte(byte(n))
+				i += 3
+			} else {
+				b.WriteByte(rest[i+1])
+				i++
+			}
+		}
+		if i >= len(rest) {
+			return "", false
+		}
+		rest = strings.TrimLeft(rest[i+1:], " \t")
+	}
+	return b.String(), true
+}
+
+// isDigits reports whether s consists of ASCII digits only.
+func isDigits(s string) bool {
+	for _, c := range []byte(s) {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeKeyData returns the canonical form of a DS or DNSKEY value: three numeric fields followed by the
+// digest or public key, which zone files often split into several blocks. The blocks are joined, and a DS
+// digest, being hex, is uppercased.
+func normalizeKeyData(rtype string, value string) string {
+	// DS: <key tag> <algorithm> <digest type> <digest>
+	// DNSKEY: <flags> <protocol> <algorithm> <public key>
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return value
+	}
+	data := strings.Join(fields[3:], "")
+	if strings.EqualFold(rtype, "DS") {
+		data = strings.ToUpper(data)
+	}
+	return strings.Join(fields[:3], " ") + " " + data
+}
+
+// wireValue returns the form of a record value that is sent to the robot. Only addresses and DNSSEC key data
+// are rewritten; hostnames are sent as given, since dropping a trailing dot may change how the robot reads them.
+func wireValue(rtype string, value string) string {
+	switch strings.ToUpper(rtype) {
+	case "AAAA", "DS", "DNSKEY":
+		return normalizeValue(rtype, value)
+	}
+	return value
+}
+
+// normalizeHostname returns the canonical form of a target hostname: lowercase and without the trailing dot,
+// so that WWW.Example.COM. and www.example.com compare equal.
+func normalizeHostname(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// relativeHost returns the host of a record name relative to the zone. Fully-qualified names inside the
+// zone, with or without trailing dot, are stripped of the zone suffix, and the zone itself as well as the
+// empty name become the apex, which the robot exports as @. Other names are returned unchanged. Wildcard
+// names keep their * label, so *.example.com in example.com becomes *.
+func relativeHost(name string, zoneName string) string {
+	zone := strings.TrimSuffix(zoneName, ".")
+	host := strings.TrimSuffix(name, ".")
+	if name == "" {
+		return apexHost
+	}
+	if zone == "" || host == "" {
+		return name
+	}
+	if strings.EqualFold(host, zone) {
+		return apexHost
+	}
+	if suffix := "." + zone; len(host) > len(suffix) && strings.EqualFold(host[len(host)-len(suffix):], suffix) {
+		return host[:len(host)-len(suffix)]
+	}
+	return name
+}
+
+// relativeHosts returns a copy of records with all hosts made relative to the zone.
+func relativeHosts(records []ResourceRecord, zoneName string) []ResourceRecord {
+	converted := make([]ResourceRecord, len(records))
+	for i, record := range records {
+		record.Host = relativeHost(record.Host, zoneName)
+		converted[i] = record
+	}
+	return converted
+}
+
+// hostCase lowercases the hosts of records in place if NormalizeCase is set and returns records.
+func (p *Provider) hostCase(records []ResourceRecord) []ResourceRecord {
+	if p.NormalizeCase {
+		for i := range records {
+			records[i].Host = strings.ToLower(records[i].Host)
+		}
+	}
+	return records
+}
@@ -0,0 +1,256 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	for _, test := range []struct {
+		rtype, value, want string
+	}{
+		{"AAAA", "2001:db8::1", "2001:db8::1"},
+		{"AAAA", "2001:0DB8:0:0:0:0:0:1", "2001:db8::1"},
+		{"aaaa", "2001:DB8:0000::0001", "2001:db8::1"},
+		{"AAAA", " 2001:db8::1 ", "2001:db8::1"},
+		{"AAAA", "192.0.2.1", "192.0.2.1"},
+		{"AAAA", "not an address", "not an address"},
+		{"CNAME", "WWW.Example.COM.", "www.example.com"},
+		{"CNAME", "www.example.com", "www.example.com"},
+		{"NS", "NS1.Example.NET.", "ns1.example.net"},
+		{"PTR", "Host.Example.COM.", "host.example.com"},
+		{"MX", "10 Mail.Example.COM.", "10 mail.example.com"},
+		{"SRV", "0 5 5060 SIP.Example.COM.", "0 5 5060 sip.example.com"},
+		{"TXT", "Mixed.Case.", "Mixed.Case."},
+	} {
+		if got := normalizeValue(test.rtype, test.value); got != test.want {
+			t.Errorf("normalizeValue(%q, %q) = %q, want %q", test.rtype, test.value, got, test.want)
+		}
+	}
+}
+
+func TestEquivalentAddresses(t *testing.T) {
+	t.Run("SetRecords", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"), fixture(t, "delrr"))
+
+		if _, err := p.SetRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "www", Type: "AAAA", Data: "2001:0DB8:0:0:0:0:0:1"}}); err != nil {
+			t.Fatalf("SetRecords() error = %v", err)
+		}
+		if got := actions(server); len(got) != 1 || got[0] != actionGetZone {
+			t.Errorf("sent %v for the address the zone holds already", got)
+		}
+	})
+
+	t.Run("DeleteRecords", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), deletedExchange)
+		p.MatchValueOnDelete = true
+
+		if _, err := p.DeleteRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "www", Type: "AAAA", Data: "2001:0DB8:0:0:0:0:0:1"}}); err != nil {
+			t.Fatalf("DeleteRecords() error = %v", err)
+		}
+		if got := sentDeletes(t, server); len(got) != 1 || got[0] != "2001:db8::1" {
+			t.Errorf("sent DELRR for %q, want 2001:db8::1", got)
+		}
+	})
+
+	t.Run("AppendRecords", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+			Action:   actionAddOrUpdateRR,
+			Response: `<zoneRequest status="ok"><rr host="www" type="AAAA" value="2001:db8::2" performedAction="added"></rr></zoneRequest>`,
+		})
+
+		if _, err := p.AppendRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "www", Type: "AAAA", Data: "2001:0DB8::0002"}}); err != nil {
+			t.Fatalf("AppendRecords() error = %v", err)
+		}
+		writes := sentRequests(t, server, actionAddOrUpdateRR)
+		if len(writes) != 1 || writes[0].Records[0].Value != "2001:db8::2" {
+			t.Errorf("sent ADDORUPDATERR %+v, want the address as 2001:db8::2", writes)
+		}
+	})
+}
+
+func TestEquivalentHostnames(t *testing.T) {
+	zone := zoneExchange(`<rr host="alias" type="CNAME" value="www.example.com."></rr><rr host="@" type="MX" value="10 mail.example.com."></rr>`)
+	for _, record := range []libdns.RR{
+		{Name: "alias", Type: "CNAME", Data: "WWW.Example.COM"},
+		{Name: "alias", Type: "CNAME", Data: "www.example.com"},
+		{Name: "Alias", Type: "cname", Data: "WWW.EXAMPLE.COM."},
+		{Name: "@", Type: "MX", Data: "10 Mail.Example.COM"},
+	} {
+		t.Run(record.Type+" "+record.Data, func(t *testing.T) {
+			p, server := newTestProvider(t, zone, fixture(t, "addorupdaterr"), deletedExchange)
+
+			if _, err := p.SetRecords(context.Background(), testZone, []libdns.Record{record}); err != nil {
+				t.Fatalf("SetRecords() error = %v", err)
+			}
+			if got := actions(server); len(got) != 1 || got[0] != actionGetZone {
+				t.Errorf("SetRecords() sent %v for the target the zone holds already", got)
+			}
+
+			p.MatchValueOnDelete = true
+			if _, err := p.DeleteRecords(context.Background(), testZone, []libdns.Record{record}); err != nil {
+				t.Fatalf("DeleteRecords() error = %v", err)
+			}
+			if got := sentDeletes(t, server); len(got) != 1 {
+				t.Errorf("DeleteRecords() sent DELRR for %q, want the stored record", got)
+			}
+		})
+	}
+}
+
+func TestRelativeHost(t *testing.T) {
+	for _, test := range []struct {
+		name, zone, want string
+	}{
+		{"", "example.com.", "@"},
+		{"@", "example.com.", "@"},
+		{"example.com", "example.com.", "@"},
+		{"example.com.", "example.com", "@"},
+		{"EXAMPLE.com.", "example.com.", "@"},
+		{"*", "example.com.", "*"},
+		{"*.example.com", "example.com.", "*"},
+		{"*.example.com.", "example.com.", "*"},
+		{"*.dev.example.com.", "example.com.", "*.dev"},
+		{"*.dev", "example.com.", "*.dev"},
+		{"www", "example.com.", "www"},
+		{"www.example.com", "example.com.", "www"},
+		{"www.example.com.", "example.com", "www"},
+		{"WWW.Example.COM.", "example.com.", "WWW"},
+		{"a.b.example.com.", "example.com.", "a.b"},
+		{"www.example.org.", "example.com.", "www.example.org."},
+		{"wwwexample.com.", "example.com.", "wwwexample.com."},
+		{"www.example.com.example.com.", "example.com.", "www.example.com"},
+	} {
+		if got := relativeHost(test.name, test.zone); got != test.want {
+			t.Errorf("relativeHost(%q, %q) = %q, want %q", test.name, test.zone, got, test.want)
+		}
+	}
+}
+
+func TestApexRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	p, server := newTestProvider(t,
+		zoneExchange(""), zoneExchange(""), zoneExchange(""),
+		robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="ok"><rr host="@" type="TXT" value="v=spf1 -all" performedAction="added"></rr></zoneRequest>`},
+		zoneExchange(`<rr host="@" type="TXT" value="v=spf1 -all"></rr>`),
+	)
+
+	for _, name := range []string{"", "@", testZone} {
+		if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: name, Type: "TXT", Data: "v=spf1 -all"}}); err != nil {
+			t.Fatalf("AppendRecords() of %q error = %v", name, err)
+		}
+	}
+	writes := sentRequests(t, server, actionAddOrUpdateRR)
+	if len(writes) != 3 {
+		t.Fatalf("sent %d ADDORUPDATERR requests, want 3", len(writes))
+	}
+	for _, write := range writes {
+		if host := write.Records[0].Host; host != "@" {
+			t.Errorf("sent ADDORUPDATERR with host %q, want @", host)
+		}
+	}
+
+	records, err := p.GetRecords(ctx, testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].RR().Name != "@" {
+		t.Errorf("GetRecords() = %v, want the record at @", records)
+	}
+}
+
+func TestWildcardRoundTrip(t *testing.T) {
+	for _, record := range []libdns.RR{
+		{Name: "*", Type: "A", Data: "192.0.2.1"},
+		{Name: "*.example.com.", Type: "A", Data: "192.0.2.1"},
+		{Name: "*.dev", Type: "TXT", Data: "wildcard"},
+	} {
+		t.Run(record.Name+" "+record.Type, func(t *testing.T) {
+			ctx := context.Background()
+			host := relativeHost(record.Name, testZone)
+			attributes := `host="` + host + `" type="` + record.Type + `" value="` + record.Data + `"`
+			p, server := newTestProvider(t,
+				zoneExchange(""),
+				robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="ok"><rr ` + attributes + ` performedAction="added"></rr></zoneRequest>`},
+				zoneExchange(`<rr `+attributes+`></rr>`),
+			)
+
+			if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{record}); err != nil {
+				t.Fatalf("AppendRecords() error = %v", err)
+			}
+			if writes := sentRequests(t, server, actionAddOrUpdateRR); len(writes) != 1 || writes[0].Records[0].Host != host {
+				t.Errorf("sent ADDORUPDATERR %+v, want host %q", writes, host)
+			}
+			records, err := p.GetRecords(ctx, testZone)
+			if err != nil {
+				t.Fatalf("GetRecords() error = %v", err)
+			}
+			if len(records) != 1 || records[0].RR().Name != host {
+				t.Errorf("GetRecords() = %v, want the record at %q", records, host)
+			}
+		})
+	}
+}
+
+func TestFullyQualifiedResponseHosts(t *testing.T) {
+	t.Run("GetRecords", func(t *testing.T) {
+		p, _ := newTestProvider(t, zoneExchange(
+			`<rr host="www" type="A" value="192.0.2.1"></rr>`+
+				`<rr host="mail.example.com" type="A" value="192.0.2.2"></rr>`+
+				`<rr host="ftp.example.com." type="A" value="192.0.2.3"></rr>`+
+				`<rr host="example.com." type="TXT" value="apex"></rr>`+
+				`<rr host="@" type="MX" value="10 mail.example.com."></rr>`,
+		))
+
+		records, err := p.GetRecords(context.Background(), testZone)
+		if err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+		var names []string
+		for _, record := range records {
+			names = append(names, record.RR().Name)
+		}
+		if want := []string{"www", "mail", "ftp", "@", "@"}; !slices.Equal(names, want) {
+			t.Errorf("GetRecords() names = %q, want %q", names, want)
+		}
+	})
+
+	t.Run("AppendRecords", func(t *testing.T) {
+		p, _ := newTestProvider(t, zoneExchange(""), robottest.Exchange{
+			Action:   actionAddOrUpdateRR,
+			Response: `<zoneRequest status="ok"><rr host="www.example.com." type="A" value="192.0.2.1" performedAction="added"></rr></zoneRequest>`,
+		})
+
+		records, err := p.AppendRecords(context.Background(), testZone, aRecords("192.0.2.1"))
+		if err != nil {
+			t.Fatalf("AppendRecords() error = %v", err)
+		}
+		if len(records) != 1 || records[0].RR().Name != "www" {
+			t.Errorf("AppendRecords() = %v, want www relative to the zone", records)
+		}
+	})
+}
+
+func TestFullyQualifiedInputNames(t *testing.T) {
+	for _, name := range []string{"www.example.com", "www.example.com.", "WWW.EXAMPLE.COM."} {
+		t.Run(name, func(t *testing.T) {
+			p, server := newTestProvider(t, zoneExchange(""), robottest.Exchange{
+				Action:   actionAddOrUpdateRR,
+				Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.1" performedAction="added"></rr></zoneRequest>`,
+			})
+
+			if _, err := p.AppendRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: name, Type: "A", Data: "192.0.2.1"}}); err != nil {
+				t.Fatalf("AppendRecords() error = %v", err)
+			}
+			writes := sentRequests(t, server, actionAddOrUpdateRR)
+			if len(writes) != 1 || !strings.EqualFold(writes[0].Records[0].Host, "www") {
+				t.Errorf("sent ADDORUPDATERR %+v, want host www", writes)
+			}
+		})
+	}
+}
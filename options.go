@@ -0,0 +1,82 @@
+package libdns_kyberio
+
+import (
+	"log/slog"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Option configures a Provider created with New or Clone.
+type Option func(*Provider)
+
+// New returns a Provider configured by the given options. It is the recommended way to create a
+// Provider; the exported fields remain available for backward compatibility.
+func New(opts ...Option) *Provider {
+	p := &Provider{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Clone returns a new Provider with the configuration of p, modified by the given options.
+// Only the exported configuration is copied; caches, counters and the HTTP client built from
+// the settings are not shared with p.
+func (p *Provider) Clone(opts ...Option) *Provider {
+	c := &Provider{}
+	src := reflect.ValueOf(p).Elem()
+	dst := reflect.ValueOf(c).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if src.Type().Field(i).IsExported() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithKey sets the DDNS key used to authenticate with the robot.
+func WithKey(key string) Option {
+	return func(p *Provider) {
+		p.APIToken = key
+	}
+}
+
+// WithEndpoint sets the URL of the robot.
+func WithEndpoint(endpoint string) Option {
+	return func(p *Provider) {
+		p.Endpoint = endpoint
+	}
+}
+
+// WithRootZoneEndpoint sets the URL used for getRootZone lookups.
+func WithRootZoneEndpoint(endpoint string) Option {
+	return func(p *Provider) {
+		p.RootZoneEndpoint = endpoint
+	}
+}
+
+// WithTimeout sets the total duration allowed for a single request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.Timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for all requests. The connection and TLS settings of the
+// provider are ignored in that case.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.HTTPClient = client
+	}
+}
+
+// WithLogger sets the logger receiving diagnostic messages.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Provider) {
+		p.Logger = logger
+	}
+}
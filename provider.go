@@ -2,33 +2,785 @@ package libdns_kyberio
 
 import (
 	"context"
+	"crypto/tls"
 	"github.com/libdns/libdns"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
 )
 
 // Provider facilitates DNS record manipulation with sdns (Kyberio Domainrobot)
 type Provider struct {
+	// APIToken is the DDNS key used to authenticate with the robot. A key set on the context with
+	// WithDDNSKey takes precedence.
 	APIToken string `json:"api_token,omitempty"`
+
+	// Endpoint is the URL of the robot. Defaults to https://robot.s-dns.de:8488/.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// RootZoneEndpoint is the URL used for getRootZone lookups, for deployments that serve them separately
+	// from the zone actions, e.g. behind another port or proxy path. Defaults to Endpoint.
+	RootZoneEndpoint string `json:"root_zone_endpoint,omitempty"`
+
+	// ContentType is sent as the Content-Type header of all requests, e.g. text/xml or
+	// application/xml; charset=ISO-8859-1 for robots that expect it. Defaults to application/xml.
+	ContentType string `json:"content_type,omitempty"`
+
+	// HTTPClient, if set, is used for all requests instead of a client built from the connection
+	// and TLS settings below.
+	HTTPClient *http.Client `json:"-"`
+
+	// Logger receives diagnostic messages. If nil, nothing is logged.
+	Logger *slog.Logger `json:"-"`
+
+	// TLSConfig is used for connections to the robot, e.g. to trust an internal CA or to pin
+	// the server certificate. If nil, the system roots are used. Certificates are always verified
+	// unless TLSConfig explicitly disables it.
+	TLSConfig *tls.Config `json:"-"`
+
+	// Timeout limits the total duration of a single request, including reading the response.
+	// Defaults to 30 seconds.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// DialTimeout limits the time spent establishing a TCP connection. Defaults to 10 seconds.
+	DialTimeout time.Duration `json:"dial_timeout,omitempty"`
+
+	// TLSHandshakeTimeout limits the time spent on the TLS handshake. Defaults to 10 seconds.
+	TLSHandshakeTimeout time.Duration `json:"tls_handshake_timeout,omitempty"`
+
+	// ResponseHeaderTimeout limits the time spent waiting for the response headers after the
+	// request has been written. Defaults to 30 seconds.
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"`
+
+	// IdleConnTimeout is the maximum time an idle keep-alive connection is kept open.
+	// Defaults to 90 seconds.
+	IdleConnTimeout time.Duration `json:"idle_conn_timeout,omitempty"`
+
+	// DisableKeepAlives opens a new connection for every request.
+	DisableKeepAlives bool `json:"disable_keep_alives,omitempty"`
+
+	// ZoneCacheTTL enables reusing a fetched zone for consecutive operations within the given duration,
+	// e.g. a SetRecords followed by a GetRecords. The cached zone is dropped after every successful write
+	// to it. Zero disables the cache.
+	ZoneCacheTTL time.Duration `json:"zone_cache_ttl,omitempty"`
+
+	// ResolveZone makes the record methods look up the zone managing the passed zone name with getRootZone.
+	// The zone may then be any name within a managed zone, e.g. a.b.example.com within example.com, and
+	// record names are relative to that name or fully qualified. Results are cached for the lifetime of the
+	// provider. The ResourceRecord methods use the zone as given, since their hosts are relative to the
+	// zone managed by the robot.
+	ResolveZone bool `json:"resolve_zone,omitempty"`
+
+	// Zones lists the zones managed by the robot, e.g. example.com and sub.example.com. If set, ResolveZone
+	// picks the zone among them by suffix match, according to ZoneMatch, and only asks the robot for names
+	// that match none of them.
+	Zones []string `json:"zones,omitempty"`
+
+	// ZoneMatch selects the zone among several Zones matching a name: ZoneMatchLongest, the default, picks
+	// the most specific zone, ZoneMatchShortest the least specific one.
+	ZoneMatch string `json:"zone_match,omitempty"`
+
+	// DeduplicateRecords drops repeated records from GetRecords results, keeping the first occurrence.
+	// Records are considered equal if name, type, value and TTL match. Off by default, so the records
+	// are returned exactly as the robot sent them.
+	DeduplicateRecords bool `json:"deduplicate_records,omitempty"`
+
+	// MaxRequestBytes limits the size of a request body. Larger batches fail with ErrRequestTooLarge
+	// before anything is sent. Defaults to 1 MiB.
+	MaxRequestBytes int `json:"max_request_bytes,omitempty"`
+
+	// MaxResponseBytes limits the size of a response body, guarding against endpoints that send endless
+	// data. Larger responses fail with ErrResponseTooLarge and are not retried. Defaults to 64 MiB, far
+	// more than the export of any zone the robot hosts.
+	MaxResponseBytes int `json:"max_response_bytes,omitempty"`
+
+	// Confirm makes every add or update fetch the zone afterwards and verify that each written record
+	// is present with the expected value, failing with ErrNotConfirmed otherwise. This catches records
+	// the robot silently discarded, at the cost of an extra request.
+	Confirm bool `json:"confirm,omitempty"`
+
+	// DefaultZone is used by the methods when they are called with an empty zone, which simplifies
+	// single-zone setups.
+	DefaultZone string `json:"default_zone,omitempty"`
+
+	// IgnoreMissingOnReplace makes ReplaceRecord a no-op returning an empty record if the old value does
+	// not exist, instead of failing with ErrRecordNotFound.
+	IgnoreMissingOnReplace bool `json:"ignore_missing_on_replace,omitempty"`
+
+	// NormalizeCase lowercases record names before they are sent, in case the robot matches names
+	// case-sensitively, so WWW and www always address the same records. Fetched records are compared
+	// case-insensitively either way.
+	NormalizeCase bool `json:"normalize_case,omitempty"`
+
+	// ParseRecords makes GetRecords, GetRecordsByType and GetRecordsByName return the typed structs of
+	// libdns, like libdns.Address or libdns.MX, instead of libdns.RR. Records of other types, or with data
+	// libdns cannot parse, are still returned as libdns.RR.
+	ParseRecords bool `json:"parse_records,omitempty"`
+
+	// AbsoluteNames makes GetRecords, GetRecordsByType and GetRecordsByName return fully qualified names,
+	// like www.example.com. and example.com. for the apex, instead of names relative to the zone. This is
+	// meant for tools displaying records; libdns callers expect relative names, so it is off by default.
+	AbsoluteNames bool `json:"absolute_names,omitempty"`
+
+	// OmitTrailingDot leaves the trailing dot off the names returned with AbsoluteNames.
+	OmitTrailingDot bool `json:"omit_trailing_dot,omitempty"`
+
+	// UnicodeNames makes GetRecords, GetRecordsByType and GetRecordsByName return internationalized names
+	// as U-labels, like münchen, instead of the A-labels the robot stores, like xn--mnchen-3ya. Names passed
+	// to the provider are converted to A-labels either way.
+	UnicodeNames bool `json:"unicode_names,omitempty"`
+
+	// MinTTL is the lowest TTL written to the robot. Lower TTLs requested for records are raised to it.
+	// Records without a TTL follow the zone TTL, which the robot applies unless a record carries its own;
+	// if the zone TTL is below MinTTL, they are written with MinTTL as their own TTL instead. Zero disables
+	// the floor.
+	MinTTL time.Duration `json:"min_ttl,omitempty"`
+
+	// DefaultTTL is the TTL of records without their own TTL if the zone reports an SOA MTTL of 0, which
+	// would otherwise give them a TTL of 0. Without it, such zones are logged as a warning.
+	DefaultTTL time.Duration `json:"default_ttl,omitempty"`
+
+	// RejectZeroTTL makes reading a zone with an SOA MTTL of 0 fail with ErrZeroTTL instead of logging a
+	// warning, unless DefaultTTL is set.
+	RejectZeroTTL bool `json:"reject_zero_ttl,omitempty"`
+
+	// MatchTTLOnDelete makes DeleteRecords only delete records whose TTL equals the TTL of the passed
+	// record, if that has one. By default records are matched on name, type and value alone.
+	MatchTTLOnDelete bool `json:"match_ttl_on_delete,omitempty"`
+
+	// MatchValueOnDelete makes DeleteRecords only delete records whose value matches the passed record, e.g.
+	// so cleaning up an ACME challenge never removes the TXT record of a concurrent challenge. Records
+	// without a value, which otherwise delete all records of their name and type, delete nothing, and
+	// records whose value the zone does not hold are skipped without an error.
+	MatchValueOnDelete bool `json:"match_value_on_delete,omitempty"`
+
+	// MaxDeletes caps the records SetRecords may delete from the RRsets it replaces. A set exceeding it, e.g.
+	// because calling code passed a partial list, fails with ErrTooManyDeletes before anything is written.
+	// Zero disables the limit.
+	MaxDeletes int `json:"max_deletes,omitempty"`
+
+	// DeleteBatchSize splits DeleteRecords and DeleteRecordsWithResults into DELRR requests of at most
+	// this many input records, sent one after the other. If a request fails, e.g. because the context
+	// deadline passes, the records deleted by the earlier requests are returned along with the error.
+	// Zero sends all records in a single request.
+	DeleteBatchSize int `json:"delete_batch_size,omitempty"`
+
+	// MaxConcurrency bounds the number of requests an operation spanning several zones or batches keeps in
+	// flight. Defaults to 4 if not set.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	// MaxRetries is the number of times a request is sent again after a network error or a 429 or 5xx
+	// status. Defaults to 0, which disables retries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// RetryBudget caps the retries of all requests made by one method call together, so an operation
+	// sending many requests during an outage does not retry each of them MaxRetries times. Defaults to 0,
+	// which leaves only MaxRetries in effect.
+	RetryBudget int `json:"retry_budget,omitempty"`
+
+	// DisableRetryJitter makes retries wait the plain exponential backoff. By default the wait is randomized
+	// between zero and the backoff, so many clients hitting the same outage spread their retries.
+	DisableRetryJitter bool `json:"disable_retry_jitter,omitempty"`
+
+	// ExpectContinue makes large requests, like bulk ADDORUPDATERR batches, carry Expect: 100-continue, so
+	// the robot can reject them, e.g. for a bad key, before the body is sent. A custom HTTPClient must set
+	// ExpectContinueTimeout on its transport for the header to take effect. Off by default.
+	ExpectContinue bool `json:"expect_continue,omitempty"`
+
+	// KeyProvider, if set, supplies the DDNS key instead of APIToken, so a rotating key can be picked up
+	// without restarting. The key is cached until the robot rejects it; the failed request is then sent
+	// once more with a fresh key, and the zones cached for the rejected key are dropped. A key set on the
+	// context with WithDDNSKey takes precedence.
+	KeyProvider func(ctx context.Context) (string, error) `json:"-"`
+
+	clientOnce sync.Once
+	client     *http.Client
+
+	cacheMu   sync.Mutex
+	zoneCache map[zoneCacheKey]cachedZone
+	rootZones map[zoneCacheKey]string
+
+	keyMu     sync.Mutex
+	cachedKey string
+
+	stats counters
+
+	clock clock
 }
 
-// GetRecords lists all the records in the zone.
+// GetRecords lists all the records in the zone. Records are returned in the order the robot sent them,
+// e.g. so the first MX record stays first. Options that drop records, like DeduplicateRecords, keep
+// that order; nothing reorders records unless explicitly documented.
 func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
-	return getRecords(ctx, p.APIToken, zone)
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.getRecords(ctx, key, view.zone)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseRecords(ctx, p.unicodeNames(p.absoluteNames(view.fromRobot(records), view.callerZone()))), nil
+}
+
+// CountRecords returns the number of records in the zone, e.g. for quota dashboards, without
+// converting the records.
+func (p *Provider) CountRecords(ctx context.Context, zone string) (int, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return 0, err
+	}
+	return p.countView(ctx, key, view)
+}
+
+// GetRecordsByType lists the records of the given type in the zone, which is cheaper than GetRecords
+// if the zone is large and only e.g. the TXT records are of interest.
+func (p *Provider) GetRecordsByType(ctx context.Context, zone string, rtype string) ([]libdns.Record, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.getRecordsByType(ctx, key, view.zone, rtype)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseRecords(ctx, p.unicodeNames(p.absoluteNames(view.fromRobot(records), view.callerZone()))), nil
+}
+
+// GetRecordsByName returns the records of a name, whatever their type, e.g. before changing a host. The name
+// is relative to the zone, with @ for the apex, or fully qualified. A name without records yields an empty
+// result, not an error.
+func (p *Provider) GetRecordsByName(ctx context.Context, zone string, name string) ([]libdns.Record, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	name = view.toRobotName(relativeHost(asciiName(name), view.callerZone()))
+	records, err := p.getRecordsByName(ctx, key, view.zone, name)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseRecords(ctx, p.unicodeNames(p.absoluteNames(view.fromRobot(records), view.callerZone()))), nil
 }
 
-// AppendRecords adds records to the zone. It returns the records that were added.
+// AppendRecords adds records to the zone. It returns the records that were added, together with the
+// input records that already existed, so repeated calls succeed idempotently.
 func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return appendRecords(ctx, p.APIToken, zone, records)
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	appended, err := p.appendRecords(ctx, key, view.zone, view.toRobot(records))
+	if err != nil {
+		return nil, err
+	}
+	return view.fromRobot(appended), nil
+}
+
+// CreateRecords adds records to the zone like AppendRecords, but fails with an error wrapping
+// ErrRecordExists if the zone holds any of them already, so provisioning workflows detect collisions. In
+// that case, nothing is written.
+func (p *Provider) CreateRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	created, err := p.createRecords(ctx, key, view.zone, view.toRobot(records))
+	return view.fromRobot(created), err
 }
 
 // SetRecords sets the records in the zone, either by updating existing records or creating new ones.
-// It returns the updated records.
+// For every (name, type) pair in the input, records of the zone that are not in the input are deleted,
+// so e.g. setting two A records of a name with three leaves exactly those two. It returns the set
+// records in input order, including those that already held the requested value. The changes are not
+// atomic: the missing records are written first and the superfluous ones deleted afterwards.
 func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return setRecords(ctx, p.APIToken, zone, records)
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	set, err := p.setRecords(ctx, key, view.zone, view.toRobot(records))
+	if err != nil {
+		return nil, err
+	}
+	return view.fromRobot(set), nil
+}
+
+// AppendRecordsWithResults works like AppendRecords, but returns the outcome of every record:
+// added, or existing if the zone already held it.
+func (p *Provider) AppendRecordsWithResults(ctx context.Context, zone string, records []libdns.Record) ([]ChangeResult, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	results, err := p.appendRecordResults(ctx, key, view.zone, view.toRobot(records))
+	if err != nil {
+		return nil, err
+	}
+	return view.fromRobotResults(results), nil
+}
+
+// SetRecordsWithResults works like SetRecords, but returns the outcome of every record: added, updated,
+// unchanged if the zone already held the requested value, or deleted for records removed from the RRsets.
+// The results of the input records come first, in input order, followed by the deleted records.
+func (p *Provider) SetRecordsWithResults(ctx context.Context, zone string, records []libdns.Record) ([]ChangeResult, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	results, err := p.setRecordResults(ctx, key, view.zone, view.toRobot(records))
+	if err != nil {
+		return nil, err
+	}
+	return view.fromRobotResults(results), nil
 }
 
 // DeleteRecords deletes the records from the zone. It returns the records that were deleted.
+// A record with an empty value deletes all records of the zone with the same name and type.
 func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return deleteRecords(ctx, p.APIToken, zone, records)
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := p.deleteRecords(ctx, key, view.zone, view.toRobot(records))
+	return view.fromRobot(deleted), err
+}
+
+// DeleteRecordsWithResults works like DeleteRecords, but returns the outcome of every record: deleted,
+// not found if the zone did not hold it, or the action the robot reported for it. If a batch fails, the
+// results of the earlier batches are returned along with the error.
+func (p *Provider) DeleteRecordsWithResults(ctx context.Context, zone string, records []libdns.Record) ([]ChangeResult, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	results, err := p.deleteRecordResults(ctx, key, view.zone, view.toRobot(records))
+	return view.fromRobotResults(results), err
+}
+
+// DeleteName removes all records of the given name, e.g. when decommissioning a host, and returns the
+// deleted records. Records of other names, including subdomains of the name, are kept.
+func (p *Provider) DeleteName(ctx context.Context, zone string, name string) ([]libdns.Record, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := p.deleteName(ctx, key, view.zone, view.toRobotName(name))
+	if err != nil {
+		return nil, err
+	}
+	return view.fromRobot(deleted), nil
+}
+
+// GetRecordComments returns the comments the zone holds for its records, keyed by the records as returned
+// by GetRecords, since libdns.Record has no field for them. Comments are written with
+// AddOrUpdateResourceRecords and never affect which records match in the other methods.
+func (p *Provider) GetRecordComments(ctx context.Context, zone string) (map[libdns.RR]string, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := p.getRecordComments(ctx, key, view.zone)
+	if err != nil {
+		return nil, err
+	}
+	if view.prefix == "" {
+		return comments, nil
+	}
+	converted := make(map[libdns.RR]string, len(comments))
+	for rr, comment := range comments {
+		if name, ok := view.fromRobotName(rr.Name); ok {
+			rr.Name = name
+			converted[rr] = comment
+		}
+	}
+	return converted, nil
+}
+
+// GetResourceRecords returns the records of the zone as sent by the robot, including attributes hidden by
+// libdns.Record. Like the other ResourceRecord methods, it is specific to s-dns and not portable; prefer
+// GetRecords unless provider-specific fields are needed.
+func (p *Provider) GetResourceRecords(ctx context.Context, zone string) ([]ResourceRecord, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zoneExport, err := p.zone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	return zoneExport.records, nil
+}
+
+// AddOrUpdateResourceRecords adds or updates the given robot records in the zone, honoring the
+// KeepExisting flag of each record. This allows mixed batches in a single request, e.g. appending a
+// TXT record while overwriting an A record. It returns the records as reported by the robot,
+// including their PerformedAction. This method is specific to s-dns and not portable.
+func (p *Provider) AddOrUpdateResourceRecords(ctx context.Context, zone string, records []ResourceRecord) ([]ResourceRecord, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.addOrUpdateResourceRecords(ctx, key, zone, records)
+}
+
+// DeleteResourceRecords deletes the given robot records from the zone and returns the records as reported
+// by the robot, including their PerformedAction. Records are sent as given, without resolving empty values.
+// This method is specific to s-dns and not portable.
+func (p *Provider) DeleteResourceRecords(ctx context.Context, zone string, records []ResourceRecord) ([]ResourceRecord, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.deleteResourceRecords(ctx, key, zone, records)
+}
+
+// AddOrUpdateZones applies a change set to several zones at once. The records of every zone are sent
+// in their own request, with a bounded number of requests in flight. It returns the records reported
+// by the robot for each zone that succeeded, and the per-zone errors joined with errors.Join.
+func (p *Provider) AddOrUpdateZones(ctx context.Context, changes map[string][]libdns.Record, keepExisting bool) (map[string][]ResourceRecord, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.addOrUpdateZones(ctx, key, changes, keepExisting)
+}
+
+// GetRecordsMulti lists the records of several zones concurrently, with at most MaxConcurrency requests
+// in flight, e.g. for dashboards showing many domains. It returns the records of every zone that could be
+// fetched; the errors of the other zones are joined into the returned error, so one failing zone does
+// not hide the others. Names follow AbsoluteNames and UnicodeNames like those of GetRecords.
+func (p *Provider) GetRecordsMulti(ctx context.Context, zones []string) (map[string][]libdns.Record, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.getRecordsMulti(ctx, key, zones)
+}
+
+// GetRootZone returns the zone managed by the robot that contains the given hostname.
+func (p *Provider) GetRootZone(ctx context.Context, hostname string) (string, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.getRootZone(ctx, key, hostname)
+}
+
+// Ping checks that the robot is reachable and accepts the configured key, using a lookup without side
+// effects. The lookup is sent to Endpoint, which writes use, and to RootZoneEndpoint if it differs. A
+// rejected key is reported as an error wrapping ErrAuthFailed.
+func (p *Provider) Ping(ctx context.Context) error {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := p.lookupRootZone(ctx, p.endpoint(), key, pingHostname); err != nil {
+		return err
+	}
+	if p.rootZoneEndpoint() == p.endpoint() {
+		return nil
+	}
+	_, err = p.lookupRootZone(ctx, p.rootZoneEndpoint(), key, pingHostname)
+	return err
+}
+
+// CanWrite reports whether the key may modify the zone, e.g. for multi-tenant systems checking permissions
+// up front. It sends a write request without records, which changes nothing. If the robot denies it, the
+// error wraps ErrAuthFailed.
+func (p *Provider) CanWrite(ctx context.Context, zone string) (bool, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return false, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return false, err
+	}
+	return p.canWrite(ctx, key, zone)
+}
+
+// RefreshZone drops the cached snapshot of the zone and fetches it again, so the next operation
+// sees the current state.
+func (p *Provider) RefreshZone(ctx context.Context, zone string) error {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return err
+	}
+	p.invalidateZone(key, zone)
+	_, err = p.zone(ctx, key, zone)
+	return err
+}
+
+// Diff returns the changes SetRecords would make to the zone for the desired records, without applying them,
+// e.g. to show a plan first. Records of an RRset holding a single value that is replaced by a single other
+// value, and current records that only get a new TTL, are returned in toUpdate; all other changes are
+// additions or deletions.
+func (p *Provider) Diff(ctx context.Context, zone string, desired []libdns.Record) (toAdd, toUpdate, toDelete []libdns.Record, err error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	changes, err := p.diff(ctx, key, view.zone, view.toRobot(desired))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return view.fromRobot(changes.toAdd), view.fromRobot(changes.toUpdate), view.fromRobot(changes.toDelete), nil
+}
+
+// GetSOA returns the SOA values of the zone: refresh, retry, expire and MTTL, all in seconds.
+func (p *Provider) GetSOA(ctx context.Context, zone string) (SOA, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return SOA{}, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return SOA{}, err
+	}
+	return p.getSOA(ctx, key, zone)
+}
+
+// GetZoneInfo returns the reseller, DNSSEC state and SOA values of the zone, which the robot reports with
+// the records, so no extra request is made when the zone is cached.
+func (p *Provider) GetZoneInfo(ctx context.Context, zone string) (ZoneInfo, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return ZoneInfo{}, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return ZoneInfo{}, err
+	}
+	return p.getZoneInfo(ctx, key, zone)
+}
+
+// GetDS returns the DS records to publish in the parent zone when DNSSEC is active. The robot does not
+// hand out key material separately; the records are derived from the DNSKEY records at the zone apex, so
+// if the robot does not export them, GetDS fails with an error wrapping ErrNoKeyMaterial.
+func (p *Provider) GetDS(ctx context.Context, zone string) ([]libdns.RR, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.getDS(ctx, key, zone)
+}
+
+// ExportBIND returns the zone in the standard RFC 1035 master file format, e.g. for backups or migrating
+// to another provider.
+func (p *Provider) ExportBIND(ctx context.Context, zone string) ([]byte, error) {
+	ctx = p.withRetryBudget(ctx)
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.exportBIND(ctx, key, zone)
+}
+
+// ReplaceRecord changes the value of a single record, e.g. a dynamic A record to a new IP, and returns the
+// updated record. Other records with the same name and type are kept. If no record with the old value
+// exists, it fails with ErrRecordNotFound, or does nothing if IgnoreMissingOnReplace is set.
+func (p *Provider) ReplaceRecord(ctx context.Context, zone string, name string, rtype string, oldValue string, newValue string) (libdns.RR, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	rr, err := p.replaceRecord(ctx, key, view.zone, view.toRobotName(name), rtype, oldValue, newValue)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	if name, ok := view.fromRobotName(rr.Name); ok {
+		rr.Name = name
+	}
+	return rr, nil
+}
+
+// SwapRecord makes newValue the only value of the records with the given name and type, creating the
+// record if there is none, and returns it. The records are overwritten in place in a single request, so
+// resolvers never see the name without a record. If the zone held several values, the ones the robot
+// keeps are deleted in a second request.
+func (p *Provider) SwapRecord(ctx context.Context, zone string, name string, rtype string, newValue string) (libdns.RR, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	rr, err := p.swapRecord(ctx, key, view.zone, view.toRobotName(name), rtype, newValue)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	if name, ok := view.fromRobotName(rr.Name); ok {
+		rr.Name = name
+	}
+	return rr, nil
+}
+
+// CompareAndSwapRecord makes newValue the only value of the records with the given name and type, but only
+// if they currently hold exactly expectedValue; an empty expectedValue requires that no such record exists.
+// Otherwise nothing is written and an error wrapping ErrPreconditionFailed is returned. The current value is
+// fetched from the robot directly before the write, never from the zone cache, though the robot cannot make
+// the check and the write atomic.
+func (p *Provider) CompareAndSwapRecord(ctx context.Context, zone string, name string, rtype string, expectedValue string, newValue string) (libdns.RR, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	rr, err := p.compareAndSwapRecord(ctx, key, view.zone, view.toRobotName(name), rtype, expectedValue, newValue)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	if name, ok := view.fromRobotName(rr.Name); ok {
+		rr.Name = name
+	}
+	return rr, nil
+}
+
+// UpdateDynamicIP sets the address of a host in one call, the classic dynamic DNS update. Each IP selects
+// the A or AAAA record by its family, so an IPv4 and an IPv6 address can be given together. Stale addresses
+// of the updated types are replaced; a type without a given address is left alone. It returns the
+// resulting records.
+func (p *Provider) UpdateDynamicIP(ctx context.Context, zone string, name string, ips ...string) ([]libdns.Record, error) {
+	ctx = p.withRetryBudget(ctx)
+	key, err := p.ddnsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, err := p.resolveZone(ctx, key, zone)
+	if err != nil {
+		return nil, err
+	}
+	records, err := p.updateDynamicIP(ctx, key, view.zone, view.toRobotName(name), ips)
+	if err != nil {
+		return nil, err
+	}
+	return view.fromRobot(records), nil
+}
+
+// ListZones returns the zones configured in Zones, as fully qualified names. The robot cannot enumerate the
+// zones a key manages, so if Zones is empty ListZones fails with an error wrapping errors.ErrUnsupported.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	return p.listZones()
 }
 
 // Interface guards
@@ -37,4 +789,5 @@ var (
 	_ libdns.RecordAppender = (*Provider)(nil)
 	_ libdns.RecordSetter   = (*Provider)(nil)
 	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
 )
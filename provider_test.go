@@ -0,0 +1,138 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+// testZone is the zone of the robottest fixtures.
+const testZone = "example.com."
+
+// fixture returns the robottest fixture of the given name, failing the test if there is none.
+func fixture(t *testing.T, name string) robottest.Exchange {
+	t.Helper()
+	exchange, ok := robottest.Fixtures()[name]
+	if !ok {
+		t.Fatalf("no fixture %q", name)
+	}
+	return exchange
+}
+
+// newTestProvider returns a provider talking to a robottest.Server replaying the given exchanges. The
+// server is closed when the test ends.
+func newTestProvider(t *testing.T, exchanges ...robottest.Exchange) (*Provider, *robottest.Server) {
+	t.Helper()
+	server := robottest.NewServer(exchanges...)
+	t.Cleanup(server.Close)
+	return &Provider{APIToken: "test-key", Endpoint: server.URL}, server
+}
+
+// actions returns the actions of the requests the server received, in order.
+func actions(server *robottest.Server) []string {
+	var result []string
+	for _, request := range server.Requests() {
+		result = append(result, request.Action)
+	}
+	return result
+}
+
+// sentRequests returns the zones of the requests for action the server received, in order.
+func sentRequests(t *testing.T, server *robottest.Server, action string) []Zone {
+	t.Helper()
+	var zones []Zone
+	for _, exchange := range server.Requests() {
+		if exchange.Action != action {
+			continue
+		}
+		var request ZoneRequest
+		if err := unmarshalXML([]byte(exchange.Request), &request); err != nil {
+			t.Fatalf("decoding %s request: %v", action, err)
+		}
+		zones = append(zones, request.Zone)
+	}
+	return zones
+}
+
+// The interfaces consumers accept instead of *Provider, as documented in the package.
+var (
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordSetter   = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
+)
+
+func TestLibdnsInterfaces(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"))
+	var getter libdns.RecordGetter = p
+
+	records, err := getter.GetRecords(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("RecordGetter.GetRecords() error = %v", err)
+	}
+	if len(records) == 0 {
+		t.Error("RecordGetter.GetRecords() returned no records")
+	}
+	if got := actions(server); len(got) != 1 || got[0] != actionGetZone {
+		t.Errorf("RecordGetter.GetRecords() sent %v, want a single GETZONE", got)
+	}
+}
+
+func TestFixtureResponses(t *testing.T) {
+	www := []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}}
+	challenge := []libdns.Record{libdns.RR{Name: "_acme-challenge", Type: "TXT", Data: "token"}}
+	for _, test := range []struct {
+		fixture string
+		call    func(ctx context.Context, p *Provider) ([]libdns.Record, error)
+		want    int
+		wantErr error
+	}{
+		{"addorupdaterr", func(ctx context.Context, p *Provider) ([]libdns.Record, error) {
+			return p.AppendRecords(ctx, testZone, www)
+		}, 1, nil},
+		{"addorupdaterr-invalid", func(ctx context.Context, p *Provider) ([]libdns.Record, error) {
+			return p.AppendRecords(ctx, testZone, www)
+		}, 0, ErrInvalidRecord},
+		{"addorupdaterr-unavailable", func(ctx context.Context, p *Provider) ([]libdns.Record, error) {
+			return p.AppendRecords(ctx, testZone, www)
+		}, 0, ErrUnexpectedStatusCode},
+		{"delrr", func(ctx context.Context, p *Provider) ([]libdns.Record, error) {
+			return p.DeleteRecords(ctx, testZone, challenge)
+		}, 1, nil},
+		{"delrr-denied", func(ctx context.Context, p *Provider) ([]libdns.Record, error) {
+			return p.DeleteRecords(ctx, testZone, challenge)
+		}, 0, ErrAuthFailed},
+	} {
+		t.Run(test.fixture, func(t *testing.T) {
+			p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, test.fixture))
+
+			records, err := test.call(context.Background(), p)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error = %v, want %v", err, test.wantErr)
+			}
+			if len(records) != test.want {
+				t.Errorf("returned %d records, want %d", len(records), test.want)
+			}
+			if got := actions(server); got[len(got)-1] != fixture(t, test.fixture).Action {
+				t.Errorf("sent %v, want %s last", got, fixture(t, test.fixture).Action)
+			}
+		})
+	}
+}
+
+func TestGetRootZoneFixtures(t *testing.T) {
+	p, _ := newTestProvider(t, fixture(t, "getrootzone"))
+	zone, err := p.GetRootZone(context.Background(), "www.example.com")
+	if err != nil || zone != "example.com" {
+		t.Errorf("GetRootZone() = %q, %v, want example.com", zone, err)
+	}
+
+	p, _ = newTestProvider(t, fixture(t, "getrootzone-notfound"))
+	if _, err := p.GetRootZone(context.Background(), "www.example.org"); !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("GetRootZone() error = %v, want %v", err, ErrZoneNotFound)
+	}
+}
@@ -0,0 +1,130 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// replaceRecord changes the value of one record of the zone from oldValue to newValue. If the record is the
+// only one of its RRset, it is overwritten in place with a single ADDORUPDATERR. Otherwise the new value is
+// added next to the other values and the old value deleted afterwards.
+func (p *Provider) replaceRecord(ctx context.Context, ddnsKey string, zoneName string, name string, rtype string, oldValue string, newValue string) (libdns.RR, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	ttl := time.Duration(zoneExport.ttl) * time.Second
+
+	old := toResourceRecord(libdns.RR{Name: name, Type: rtype, Data: oldValue}, zoneName)
+	replacement := toResourceRecord(libdns.RR{Name: name, Type: rtype, Data: newValue}, zoneName)
+	replacement.TTL = p.floorTTL(replacement.TTL, zoneExport.ttl)
+	set := groupRRsets([]ResourceRecord{old}, zoneExport.records)[0]
+
+	var stored *ResourceRecord
+	for i := range set.current {
+		if sameRecord(set.current[i], old) {
+			stored = &set.current[i]
+			break
+		}
+	}
+	if stored == nil {
+		if p.IgnoreMissingOnReplace {
+			return libdns.RR{}, nil
+		}
+		return libdns.RR{}, fmt.Errorf("%w: %s %s %q", ErrRecordNotFound, name, rtype, oldValue)
+	}
+	if sameRecord(*stored, replacement) {
+		return toLibdnsRR(*stored, ttl), nil
+	}
+
+	replacement.Comment = stored.Comment
+	replacement.KeepExisting = len(set.current) > 1
+	if _, err := p.writeResourceRecords(ctx, ddnsKey, zoneName, []ResourceRecord{replacement}, zoneExport.ttl); err != nil {
+		return libdns.RR{}, err
+	}
+
+	if replacement.KeepExisting {
+		// the other values of the RRset were kept, so the old value has to go explicitly
+		oldRecord := ResourceRecord{Host: stored.Host, Type: stored.Type, Value: stored.Value, Class: stored.Class}
+		if _, err := p.deleteResourceRecords(ctx, ddnsKey, zoneName, []ResourceRecord{oldRecord}); err != nil {
+			return libdns.RR{}, fmt.Errorf("new value added, but deleting the old value failed: %w", err)
+		}
+	}
+
+	replacement.KeepExisting = false
+	return toLibdnsRR(replacement, ttl), nil
+}
+
+// swapRecord makes newValue the only value of the RRset of name and type. The RRset is overwritten in place
+// with a single ADDORUPDATERR without keepExisting, so there is no moment without a record. Values the robot
+// keeps nonetheless, e.g. of an RRset holding several values, are deleted in a second request afterwards.
+func (p *Provider) swapRecord(ctx context.Context, ddnsKey string, zoneName string, name string, rtype string, newValue string) (libdns.RR, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	ttl := time.Duration(zoneExport.ttl) * time.Second
+
+	replacement := toResourceRecord(libdns.RR{Name: name, Type: rtype, Data: newValue}, zoneName)
+	replacement.TTL = p.floorTTL(replacement.TTL, zoneExport.ttl)
+	set := groupRRsets([]ResourceRecord{replacement}, zoneExport.records)[0]
+	extras := set.extra()
+	if stored, ok := storedRecord(set.current, replacement); ok && len(extras) == 0 {
+		return toLibdnsRR(stored, ttl), nil
+	}
+
+	if _, err := p.writeResourceRecords(ctx, ddnsKey, zoneName, []ResourceRecord{replacement}, zoneExport.ttl); err != nil {
+		return libdns.RR{}, err
+	}
+	if len(set.current) <= 1 {
+		// a single value was overwritten in place, nothing is left to delete
+		return toLibdnsRR(replacement, ttl), nil
+	}
+	if _, err := p.deleteResourceRecords(ctx, ddnsKey, zoneName, extras); err != nil {
+		return libdns.RR{}, fmt.Errorf("new value written, but deleting the old values failed: %w", err)
+	}
+
+	return toLibdnsRR(replacement, ttl), nil
+}
+
+// compareAndSwapRecord makes newValue the only value of the RRset of name and type, provided the RRset holds
+// exactly expectedValue, or no record at all if expectedValue is empty. The zone is fetched bypassing the
+// cache, filtered by type, directly before the write, so the window for a concurrent change is as small as
+// the robot allows; it has no conditional write to close it entirely.
+func (p *Provider) compareAndSwapRecord(ctx context.Context, ddnsKey string, zoneName string, name string, rtype string, expectedValue string, newValue string) (libdns.RR, error) {
+	zoneExport, err := p.getZoneByType(ctx, ddnsKey, zoneName, rtype)
+	if err != nil {
+		return libdns.RR{}, err
+	}
+	ttl := time.Duration(zoneExport.ttl) * time.Second
+
+	replacement := toResourceRecord(libdns.RR{Name: name, Type: rtype, Data: newValue}, zoneName)
+	replacement.TTL = p.floorTTL(replacement.TTL, zoneExport.ttl)
+	set := groupRRsets([]ResourceRecord{replacement}, zoneExport.records)[0]
+
+	current := make([]string, 0, len(set.current))
+	for _, record := range set.current {
+		current = append(current, record.Value)
+	}
+	switch {
+	case expectedValue == "" && len(set.current) == 0:
+	case expectedValue != "" && len(set.current) == 1 &&
+		sameRecord(set.current[0], toResourceRecord(libdns.RR{Name: name, Type: rtype, Data: expectedValue}, zoneName)):
+	default:
+		return libdns.RR{}, fmt.Errorf("%w: %s %s holds %q, expected %q", ErrPreconditionFailed, name, rtype, current, expectedValue)
+	}
+
+	if stored, ok := storedRecord(set.current, replacement); ok {
+		return toLibdnsRR(stored, ttl), nil
+	}
+	if len(set.current) == 1 {
+		replacement.Comment = set.current[0].Comment
+	}
+	if _, err := p.writeResourceRecords(ctx, ddnsKey, zoneName, []ResourceRecord{replacement}, zoneExport.ttl); err != nil {
+		return libdns.RR{}, err
+	}
+	return toLibdnsRR(replacement, ttl), nil
+}
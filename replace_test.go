@@ -0,0 +1,110 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+)
+
+// wwwUpdated answers ADDORUPDATERR with www A 192.0.2.7 as updated.
+var wwwUpdated = robottest.Exchange{
+	Action:   actionAddOrUpdateRR,
+	Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.7" performedAction="updated"></rr></zoneRequest>`,
+}
+
+func TestSwapRecordSingleValue(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"), wwwUpdated, fixture(t, "delrr"))
+
+	rr, err := p.SwapRecord(context.Background(), testZone, "www", "A", "192.0.2.7")
+	if err != nil {
+		t.Fatalf("SwapRecord() error = %v", err)
+	}
+	if rr.Data != "192.0.2.7" {
+		t.Errorf("SwapRecord() = %v", rr)
+	}
+	want := []string{actionGetZone, actionAddOrUpdateRR}
+	if got := actions(server); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+	if writes := sentRequests(t, server, actionAddOrUpdateRR); len(writes) != 1 || writes[0].Records[0].KeepExisting {
+		t.Errorf("sent ADDORUPDATERR %+v, want an overwrite without keepExisting", writes)
+	}
+}
+
+func TestSwapRecordSeveralValues(t *testing.T) {
+	p, server := newTestProvider(t,
+		zoneExchange(`<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.2"></rr>`),
+		wwwUpdated,
+		robottest.Exchange{Action: actionDeleteRR, Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.2" performedAction="deleted"></rr></zoneRequest>`},
+	)
+
+	if _, err := p.SwapRecord(context.Background(), testZone, "www", "A", "192.0.2.7"); err != nil {
+		t.Fatalf("SwapRecord() error = %v", err)
+	}
+	deletes := sentRequests(t, server, actionDeleteRR)
+	if len(deletes) != 1 || len(values(deletes[0].Records)) != 2 {
+		t.Errorf("sent DELRR %+v, want one for the two old values", deletes)
+	}
+}
+
+func TestSwapRecordUnchanged(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"))
+
+	if _, err := p.SwapRecord(context.Background(), testZone, "www", "A", "192.0.2.1"); err != nil {
+		t.Fatalf("SwapRecord() error = %v", err)
+	}
+	if got := actions(server); len(got) != 1 {
+		t.Errorf("sent %v, want only GETZONE", got)
+	}
+}
+
+func TestCompareAndSwapRecord(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		expected string
+		wantErr  error
+		writes   int
+	}{
+		{"match", "192.0.2.1", nil, 1},
+		{"mismatch", "192.0.2.9", ErrPreconditionFailed, 0},
+		{"absent expected", "", ErrPreconditionFailed, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, server := newTestProvider(t, fixture(t, "getzone"), wwwUpdated)
+			p.ZoneCacheTTL = time.Minute
+
+			// a cached snapshot must not stand in for the current value
+			if _, err := p.GetRecords(ctx, testZone); err != nil {
+				t.Fatalf("GetRecords() error = %v", err)
+			}
+			_, err := p.CompareAndSwapRecord(ctx, testZone, "www", "A", test.expected, "192.0.2.7")
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("CompareAndSwapRecord() error = %v, want %v", err, test.wantErr)
+			}
+			if got := count(actions(server), actionGetZone); got != 2 {
+				t.Errorf("sent %d GETZONE requests, want a fresh one for the comparison", got)
+			}
+			if got := len(sentRequests(t, server, actionAddOrUpdateRR)); got != test.writes {
+				t.Errorf("sent %d ADDORUPDATERR requests, want %d", got, test.writes)
+			}
+		})
+	}
+}
+
+func TestCompareAndSwapRecordCreate(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+		Action:   actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok"><rr host="new" type="A" value="192.0.2.7" performedAction="added"></rr></zoneRequest>`,
+	})
+
+	if _, err := p.CompareAndSwapRecord(context.Background(), testZone, "new", "A", "", "192.0.2.7"); err != nil {
+		t.Fatalf("CompareAndSwapRecord() error = %v", err)
+	}
+	if got := len(sentRequests(t, server, actionAddOrUpdateRR)); got != 1 {
+		t.Errorf("sent %d ADDORUPDATERR requests, want 1", got)
+	}
+}
@@ -0,0 +1,86 @@
+package libdns_kyberio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// xmlDeclaration precedes the body of robot requests, declaring the encoding of the request. Non-ASCII
+// characters are sent as character references, so the body is valid in any ASCII-compatible encoding.
+const xmlDeclaration = `<?xml version="1.0" encoding="ISO-8859-1"?>` + "\n"
+
+// undeclaredActions are sent without the XML declaration, as they always have been. Every zone action,
+// GETZONE included, declares its encoding; getRootZone requests go to RootZoneEndpoint, which may be served
+// separately and has only ever seen them undeclared.
+var undeclaredActions = map[string]bool{
+	actionGetRootZone: true,
+}
+
+// post marshals payload and sends it to endpoint as a request for action. It returns the raw response body
+// and the ID of the request. All robot requests are built here, so they share encoding, headers, size limit,
+// retries and key rotation.
+func (p *Provider) post(ctx context.Context, endpoint string, action string, payload any) (body []byte, requestID string, err error) {
+	xmlData, err := p.marshalXML(ctx, payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal XML: %w", err)
+	}
+
+	data := escapeNonASCII(xmlData)
+	if !undeclaredActions[action] {
+		data = append([]byte(xmlDeclaration), data...)
+	}
+	if err := p.checkRequestSize(action, data); err != nil {
+		return nil, "", err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	body, err = p.doRequest(request, action)
+	return body, request.Header.Get(requestIDHeader), err
+}
+
+// do sends the zone action of zone to the robot and returns the decoded response. A response status other
+// than ok is returned as an *APIError.
+func (p *Provider) do(ctx context.Context, zone Zone) (zoneEnvelope, error) {
+	body, err := p.doRaw(ctx, zone)
+	if err != nil {
+		return zoneEnvelope{}, err
+	}
+
+	response, err := decodeZoneResponse(body)
+	if err != nil {
+		return zoneEnvelope{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return response, nil
+}
+
+// doRaw works like do, but returns the raw response body, for callers that decode it as a stream. Only the
+// status is read before, so every zone action fails the same way, however its response is decoded.
+func (p *Provider) doRaw(ctx context.Context, zone Zone) ([]byte, error) {
+	body, requestID, err := p.post(ctx, p.endpoint(), zone.Action, ZoneRequest{Zone: zone})
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := responseStatus(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	if !strings.EqualFold(status, "ok") {
+		return nil, &APIError{
+			Action:     zone.Action,
+			Status:     status,
+			StatusCode: http.StatusOK,
+			Body:       string(body),
+			RequestID:  requestID,
+		}
+	}
+
+	return body, nil
+}
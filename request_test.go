@@ -0,0 +1,266 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestZoneActionsShareStatusHandling(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		response string
+		want     error
+	}{
+		{"root status", `<zoneRequest status="denied"></zoneRequest>`, ErrAuthFailed},
+		{"nested status", `<zoneRequest><zone name="example.com" status="nozone"></zone></zoneRequest>`, ErrZoneNotFound},
+		{"records before nested status", `<zoneRequest><warning>w</warning><zone name="example.com" status="quota"></zone></zoneRequest>`, ErrQuotaExceeded},
+		{"missing status", `<zoneRequest><rr host="www" type="A" value="192.0.2.1"></rr></zoneRequest>`, ErrMissingStatus},
+		{"unknown status", `<zoneRequest status="maintenance"></zoneRequest>`, ErrRequestFailed},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, _ := newTestProvider(t,
+				robottest.Exchange{Action: actionGetZone, Response: test.response},
+				robottest.Exchange{Action: actionAddOrUpdateRR, Response: test.response},
+			)
+
+			_, getErr := p.GetRecords(ctx, testZone)
+			_, writeErr := p.AddOrUpdateResourceRecords(ctx, testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.2"}})
+			for action, err := range map[string]error{actionGetZone: getErr, actionAddOrUpdateRR: writeErr} {
+				if !errors.Is(err, test.want) {
+					t.Errorf("%s error = %v, want %v", action, err, test.want)
+				}
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("%s error = %#v, want *APIError", action, err)
+				}
+				if apiErr.Action != action || apiErr.RequestID == "" {
+					t.Errorf("%s error has action %q and request id %q", action, apiErr.Action, apiErr.RequestID)
+				}
+			}
+		})
+	}
+}
+
+func TestZoneActionsMalformedResponse(t *testing.T) {
+	p, _ := newTestProvider(t, robottest.Exchange{Action: actionGetZone, Response: "Service Unavailable"})
+
+	_, err := p.GetRecords(context.Background(), testZone)
+	var apiErr *APIError
+	if err == nil || errors.As(err, &apiErr) {
+		t.Errorf("GetRecords() error = %v, want a decoding error", err)
+	}
+}
+
+func TestGetZoneOKStatus(t *testing.T) {
+	p, _ := newTestProvider(t, robottest.Exchange{
+		Action:   actionGetZone,
+		Response: `<zoneRequest><zone name="example.com" status="ok"><rr host="www" type="A" value="192.0.2.1"></rr></zone></zoneRequest>`,
+	})
+
+	records, err := p.GetRecords(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].RR() != (libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: records[0].RR().TTL}) {
+		t.Errorf("GetRecords() = %v", records)
+	}
+}
+
+func TestRequestDeclarations(t *testing.T) {
+	ctx := context.Background()
+	p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"), fixture(t, "getrootzone"))
+
+	if _, err := p.GetRecords(ctx, testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if _, err := p.AddOrUpdateResourceRecords(ctx, testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.2", KeepExisting: true}}); err != nil {
+		t.Fatalf("AddOrUpdateResourceRecords() error = %v", err)
+	}
+	if _, err := p.GetRootZone(ctx, "www.example.com"); err != nil {
+		t.Fatalf("GetRootZone() error = %v", err)
+	}
+
+	for _, request := range server.Requests() {
+		declared := strings.HasPrefix(request.Request, xmlDeclaration)
+		if want := request.Action != actionGetRootZone; declared != want {
+			t.Errorf("%s request declared = %v, want %v: %s", request.Action, declared, want, request.Request)
+		}
+	}
+	if got := actions(server); len(got) != 3 {
+		t.Errorf("sent %v, want GETZONE, ADDORUPDATERR and getRootZone", got)
+	}
+}
+
+func TestXMLSpecialCharacters(t *testing.T) {
+	const value = `v=DKIM1; n="a<b>&c'd"; grüße`
+	ctx := context.Background()
+	p, server := newTestProvider(t,
+		zoneExchange(""),
+		robottest.Exchange{
+			Action:   actionAddOrUpdateRR,
+			Response: `<zoneRequest status="ok"><rr host="dkim" type="TXT" value="v=DKIM1; n=&quot;a&lt;b&gt;&amp;c&apos;d&quot;; grüße" performedAction="added"></rr></zoneRequest>`,
+		},
+		// ISO-8859-1 encodes ü and ß as single bytes
+		robottest.Exchange{
+			Action: actionGetZone,
+			Response: `<?xml version="1.0" encoding="ISO-8859-1"?>` + "\n" + `<zoneRequest status="ok"><zone name="example.com"><soa mttl="3600"></soa>` +
+				"<rr host=\"dkim\" type=\"TXT\" value=\"v=DKIM1; n=&quot;a&lt;b&gt;&amp;c&#39;d&quot;; gr\xfc\xdfe\"></rr></zone></zoneRequest>",
+		},
+	)
+
+	if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "dkim", Type: "TXT", Data: value}}); err != nil {
+		t.Fatalf("AppendRecords() error = %v", err)
+	}
+	var body string
+	for _, request := range server.Requests() {
+		if request.Action == actionAddOrUpdateRR {
+			body = request.Request
+		}
+	}
+	for _, raw := range []string{"<b>", "&c", "ü", "ß"} {
+		if strings.Contains(body, raw) {
+			t.Errorf("ADDORUPDATERR request contains %q unescaped: %s", raw, body)
+		}
+	}
+	if writes := sentRequests(t, server, actionAddOrUpdateRR); len(writes) != 1 || writes[0].Records[0].Value != value {
+		t.Errorf("sent ADDORUPDATERR %+v, want the value %q", writes, value)
+	}
+
+	records, err := p.GetRecords(ctx, testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].RR().Data != value {
+		t.Errorf("GetRecords() = %v, want the value %q", records, value)
+	}
+}
+
+func TestRequestIndentation(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		logger   *slog.Logger
+		indented bool
+	}{
+		{"without logger", nil, false},
+		{"info logger", slog.New(slog.NewTextHandler(io.Discard, nil)), false},
+		{"debug logger", slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug})), true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, fixture(t, "addorupdaterr"))
+			p.Logger = test.logger
+
+			if _, err := p.AddOrUpdateResourceRecords(context.Background(), testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.2"}}); err != nil {
+				t.Fatalf("AddOrUpdateResourceRecords() error = %v", err)
+			}
+			request := server.Requests()[0].Request
+			body := strings.TrimPrefix(request, xmlDeclaration)
+			if indented := strings.Contains(body, "\n"); indented != test.indented {
+				t.Errorf("request indented = %t, want %t: %s", indented, test.indented, request)
+			}
+			writes := sentRequests(t, server, actionAddOrUpdateRR)
+			if len(writes) != 1 || len(writes[0].Records) != 1 || writes[0].Records[0].Value != "192.0.2.2" {
+				t.Errorf("sent ADDORUPDATERR %+v, want the www record either way", writes)
+			}
+		})
+	}
+}
+
+func TestNormalizeCase(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		normalizeCase bool
+		wantHost      string
+	}{
+		{"as given", false, "WWW"},
+		{"lowercased", true, "www"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, server := newTestProvider(t, zoneExchange(""), robottest.Exchange{
+				Action:   actionAddOrUpdateRR,
+				Response: `<zoneRequest status="ok"><rr host="` + test.wantHost + `" type="A" value="192.0.2.1" performedAction="added"></rr></zoneRequest>`,
+			}, deletedExchange)
+			p.NormalizeCase = test.normalizeCase
+
+			if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "WWW", Type: "A", Data: "192.0.2.1"}}); err != nil {
+				t.Fatalf("AppendRecords() error = %v", err)
+			}
+			if _, err := p.DeleteRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "WWW", Type: "A", Data: "192.0.2.1"}}); err != nil {
+				t.Fatalf("DeleteRecords() error = %v", err)
+			}
+			if got := actions(server); !slices.Equal(got, []string{actionGetZone, actionAddOrUpdateRR, actionGetZone, actionDeleteRR}) {
+				t.Fatalf("sent %v, want a write and a delete", got)
+			}
+			for _, request := range server.Requests() {
+				if request.Action == actionGetZone {
+					continue
+				}
+				if !strings.Contains(request.Request, `host="`+test.wantHost+`"`) {
+					t.Errorf("%s request = %s, want host %s", request.Action, request.Request, test.wantHost)
+				}
+			}
+		})
+	}
+
+	t.Run("stored host", func(t *testing.T) {
+		// a record created as www is deleted as the zone holds it, whatever the case of the input
+		p, server := newTestProvider(t, fixture(t, "getzone"), deletedExchange)
+
+		if _, err := p.DeleteRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "WWW", Type: "A", Data: "192.0.2.1"}}); err != nil {
+			t.Fatalf("DeleteRecords() error = %v", err)
+		}
+		if deletes := sentRequests(t, server, actionDeleteRR); len(deletes) != 1 || deletes[0].Records[0].Host != "www" {
+			t.Errorf("sent DELRR %+v, want host www", deletes)
+		}
+	})
+}
+
+func TestRecordComments(t *testing.T) {
+	ctx := context.Background()
+	commented := `<rr host="www" type="A" value="192.0.2.1" comment="TICKET-42 &amp; owner: web"></rr>`
+	p, server := newTestProvider(t,
+		robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.1" comment="TICKET-42 &amp; owner: web" performedAction="added"></rr></zoneRequest>`},
+		zoneExchange(commented),
+		deletedExchange,
+	)
+
+	if _, err := p.AddOrUpdateResourceRecords(ctx, testZone, []ResourceRecord{{Host: "www", Type: "A", Value: "192.0.2.1", Comment: "TICKET-42 & owner: web"}}); err != nil {
+		t.Fatalf("AddOrUpdateResourceRecords() error = %v", err)
+	}
+	if body := server.Requests()[0].Request; !strings.Contains(body, `comment="TICKET-42 &amp; owner: web"`) {
+		t.Errorf("ADDORUPDATERR request = %s, want the escaped comment attribute", body)
+	}
+
+	comments, err := p.GetRecordComments(ctx, testZone)
+	if err != nil {
+		t.Fatalf("GetRecordComments() error = %v", err)
+	}
+	if want := "TICKET-42 & owner: web"; len(comments) != 1 || comments[libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: time.Hour}] != want {
+		t.Errorf("GetRecordComments() = %v, want %q for www", comments, want)
+	}
+
+	// the comment does not keep the record from matching
+	if _, err := p.SetRecords(ctx, testZone, aRecords("192.0.2.1")); err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if got := count(actions(server), actionAddOrUpdateRR); got != 1 {
+		t.Errorf("sent %d ADDORUPDATERR requests, want 1: SetRecords has nothing to write", got)
+	}
+	p.MatchValueOnDelete = true
+	if _, err := p.DeleteRecords(ctx, testZone, aRecords("192.0.2.1")); err != nil {
+		t.Fatalf("DeleteRecords() error = %v", err)
+	}
+	if got := sentDeletes(t, server); len(got) != 1 || got[0] != "192.0.2.1" {
+		t.Errorf("sent DELRR for %q, want the commented record", got)
+	}
+}
@@ -0,0 +1,107 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Delays between retries of a failed request. The delay doubles with every attempt up to maxRetryDelay.
+const (
+	baseRetryDelay = 500 * time.Millisecond
+	maxRetryDelay  = 10 * time.Second
+)
+
+// retryBudgetKey is the context key of the retry budget of an operation.
+type retryBudgetKey struct{}
+
+// retryBudget counts the retries left to all requests of one logical operation.
+type retryBudget struct {
+	left atomic.Int64
+}
+
+// withRetryBudget returns a copy of ctx carrying a fresh budget of RetryBudget retries, so that all requests
+// made for one operation, e.g. the chunks of a SetRecords, draw from the same budget. A budget already
+// present in ctx is kept.
+func (p *Provider) withRetryBudget(ctx context.Context) context.Context {
+	if p.RetryBudget <= 0 || ctx.Value(retryBudgetKey{}) != nil {
+		return ctx
+	}
+	budget := &retryBudget{}
+	budget.left.Store(int64(p.RetryBudget))
+	return context.WithValue(ctx, retryBudgetKey{}, budget)
+}
+
+// takeRetry reports whether the operation of ctx may retry a request once more and consumes one retry
+// of its budget if so. Without a budget, only MaxRetries limits the retries.
+func takeRetry(ctx context.Context) bool {
+	budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	if !ok {
+		return true
+	}
+	return budget.left.Add(-1) >= 0
+}
+
+// isTransient reports whether a failed request may succeed if it is sent again: the robot was unreachable,
+// overloaded or answered with a server error, or the TLS handshake broke off, e.g. during a restart. Errors
+// caused by the context and rejected certificates are never transient.
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || isCertificateError(err) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	// refused redirects and oversized responses do not go away either
+	return !errors.Is(err, ErrUnexpectedStatusCode) && !errors.Is(err, ErrResponseTooLarge)
+}
+
+// canRewind reports whether the body of request can be sent again.
+func canRewind(request *http.Request) bool {
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+// rewind returns a copy of request with a fresh body, for sending it again.
+func rewind(request *http.Request) (*http.Request, error) {
+	clone := request.Clone(request.Context())
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// retryDelay returns the delay before the given retry, starting at 1. Unless DisableRetryJitter is set, the
+// delay is drawn uniformly between zero and the exponential backoff ("full jitter"), so clients failing at
+// the same moment do not retry in lockstep.
+func (p *Provider) retryDelay(retry int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < retry && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	if p.DisableRetryJitter {
+		return delay
+	}
+	return time.Duration(rand.Int64N(int64(delay) + 1))
+}
+
+// isCertificateError reports whether err is caused by a certificate that failed verification, which
+// retrying cannot fix.
+func isCertificateError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verificationErr) || errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
@@ -0,0 +1,132 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRetryBudgetCapsAttempts(t *testing.T) {
+	changes := map[string][]libdns.Record{
+		"a.example.": {libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"}},
+		"b.example.": {libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"}},
+		"c.example.": {libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"}},
+	}
+	for _, test := range []struct {
+		name   string
+		budget int
+		want   int
+	}{
+		{"without budget", 0, 3 * 6}, // every zone retried MaxRetries times
+		{"with budget", 4, 3 + 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, fixture(t, "addorupdaterr-unavailable"))
+			p.MaxRetries = 5
+			p.RetryBudget = test.budget
+			p.MaxConcurrency = 1
+			p.clock = newFakeClock()
+
+			_, err := p.AddOrUpdateZones(context.Background(), changes, false)
+			if !errors.Is(err, ErrUnexpectedStatusCode) {
+				t.Fatalf("AddOrUpdateZones() error = %v, want %v", err, ErrUnexpectedStatusCode)
+			}
+			if got := count(actions(server), actionAddOrUpdateRR); got != test.want {
+				t.Errorf("sent %d ADDORUPDATERR requests, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryBudgetPerOperation(t *testing.T) {
+	p, server := newTestProvider(t, unavailable, fixture(t, "getzone"), unavailable, fixture(t, "getzone"))
+	p.MaxRetries = 1
+	p.RetryBudget = 1
+	p.clock = newFakeClock()
+
+	// each call gets a budget of its own
+	for i := 0; i < 2; i++ {
+		if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+			t.Fatalf("GetRecords() call %d error = %v", i+1, err)
+		}
+	}
+	if got := count(actions(server), actionGetZone); got != 4 {
+		t.Errorf("sent %d GETZONE requests, want 4", got)
+	}
+}
+
+// dropListener closes the first drop connections it accepts right away, breaking off their TLS handshake.
+type dropListener struct {
+	net.Listener
+	drop     int32
+	accepted atomic.Int32
+}
+
+// Accept returns the next connection that is not dropped.
+func (l *dropListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.accepted.Add(1) <= l.drop {
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
+
+// newTLSRobot starts a TLS server answering GETZONE with the zone export behind a dropListener.
+func newTLSRobot(t *testing.T, drop int32) (*httptest.Server, *dropListener) {
+	t.Helper()
+	export := fixture(t, "getzone").Response
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(export))
+	}))
+	// handshakes rejected by the client are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	listener := &dropListener{Listener: server.Listener, drop: drop}
+	server.Listener = listener
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, listener
+}
+
+func TestRetryDroppedTLSHandshake(t *testing.T) {
+	server, listener := newTLSRobot(t, 1)
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	p := &Provider{APIToken: "test-key", Endpoint: server.URL, TLSConfig: &tls.Config{RootCAs: roots}, MaxRetries: 2, clock: newFakeClock()}
+
+	if _, err := p.GetRecords(context.Background(), testZone); err != nil {
+		t.Fatalf("GetRecords() error = %v, want the retry to recover", err)
+	}
+	if got := listener.accepted.Load(); got != 2 {
+		t.Errorf("robot accepted %d connections, want 2", got)
+	}
+}
+
+func TestCertificateErrorNotRetried(t *testing.T) {
+	server, listener := newTLSRobot(t, 0)
+	p := &Provider{APIToken: "test-key", Endpoint: server.URL, MaxRetries: 2, clock: newFakeClock()}
+
+	_, err := p.GetRecords(context.Background(), testZone)
+	if !isCertificateError(err) || !strings.Contains(err.Error(), "TLS certificate of the robot not accepted") {
+		t.Fatalf("GetRecords() error = %v, want a certificate error", err)
+	}
+	if got := listener.accepted.Load(); got != 1 {
+		t.Errorf("robot accepted %d connections, want 1 without retries", got)
+	}
+}
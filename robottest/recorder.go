@@ -0,0 +1,85 @@
+package robottest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ddnsKeyPattern matches the DDNS key attribute of a request.
+var ddnsKeyPattern = regexp.MustCompile(`ddnskey="[^"]*"`)
+
+// Recorder is an http.RoundTripper that passes requests on to the robot and writes every exchange to a
+// fixture file in Dir, named after a running number and the action, e.g. 001-getzone.json. The DDNS key
+// is redacted from the stored requests. Use it as the transport of Provider.HTTPClient to capture
+// fixtures from the live robot.
+type Recorder struct {
+	Transport http.RoundTripper // Transport used for the requests, http.DefaultTransport if nil
+	Dir       string            // Directory the fixture files are written to
+
+	mu sync.Mutex
+	n  int
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	exchange := Exchange{
+		Action:     Action(body),
+		Request:    ddnsKeyPattern.ReplaceAllString(string(body), `ddnskey="REDACTED"`),
+		StatusCode: response.StatusCode,
+		Response:   string(responseBody),
+	}
+	if err := r.write(exchange); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// write stores an exchange as the next fixture file.
+func (r *Recorder) write(exchange Exchange) error {
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.n++
+	name := fmt.Sprintf("%03d-%s.json", r.n, strings.ToLower(exchange.Action))
+	r.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(r.Dir, name), append(data, '\n'), 0o644)
+}
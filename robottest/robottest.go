@@ -0,0 +1,157 @@
+// Package robottest replays recorded exchanges with the s-dns robot from an httptest.Server, so the
+// provider can be exercised without the live robot. Point Provider.Endpoint at the URL of the server.
+//
+// Exchanges are stored as JSON fixture files, one per request. New fixtures are captured from the live
+// robot with a Recorder, or written by hand; the fixtures shipped with the package cover the GETZONE,
+// ADDORUPDATERR, DELRR and getRootZone actions, including error responses, and are available through
+// Fixtures.
+package robottest
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+)
+
+// Exchange is one recorded request to the robot and its response.
+type Exchange struct {
+	Name       string `json:"name,omitempty"`        // Description of the case, e.g. "zone not found"
+	Action     string `json:"action"`                // Robot action of the request, e.g. GETZONE
+	Request    string `json:"request,omitempty"`     // Request body as sent, with the DDNS key redacted
+	StatusCode int    `json:"status_code,omitempty"` // HTTP status code of the response, 200 if empty
+	Response   string `json:"response"`              // Response body
+}
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Fixtures returns the exchanges shipped with the package, keyed by the name of the fixture file
+// without extension, e.g. "getzone" or "getzone-notfound".
+func Fixtures() map[string]Exchange {
+	exchanges, err := Load(fixtures, "fixtures/*.json")
+	if err != nil {
+		panic(err)
+	}
+	return exchanges
+}
+
+// Load reads the fixture files of fsys matching pattern, keyed by file name without extension.
+func Load(fsys fs.FS, pattern string) (map[string]Exchange, error) {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	exchanges := make(map[string]Exchange, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var exchange Exchange
+		if err := json.Unmarshal(data, &exchange); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", name, err)
+		}
+		base := path.Base(name)
+		exchanges[base[:len(base)-len(path.Ext(base))]] = exchange
+	}
+	return exchanges, nil
+}
+
+// Server is an httptest.Server answering robot requests with recorded exchanges. Each request is
+// answered by the first unused exchange with the same action; the last exchange of an action is reused
+// once all others are used. Requests without a matching exchange get a 501 response.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+	requests  []Exchange
+}
+
+// NewServer starts a server replaying the given exchanges. The caller must call Close when done.
+func NewServer(exchanges ...Exchange) *Server {
+	s := &Server{
+		exchanges: exchanges,
+		used:      make([]bool, len(exchanges)),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Requests returns the requests received so far, with the action and body of each.
+func (s *Server) Requests() []Exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Exchange(nil), s.requests...)
+}
+
+// serve answers a request with the matching exchange.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := Action(body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Exchange{Action: action, Request: string(body)})
+	match := -1
+	for i, exchange := range s.exchanges {
+		if exchange.Action != action {
+			continue
+		}
+		match = i
+		if !s.used[i] {
+			break
+		}
+	}
+	var exchange Exchange
+	if match >= 0 {
+		s.used[match] = true
+		exchange = s.exchanges[match]
+	}
+	s.mu.Unlock()
+
+	if match < 0 {
+		http.Error(w, fmt.Sprintf("robottest: no exchange for action %q", action), http.StatusNotImplemented)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	if exchange.StatusCode != 0 {
+		w.WriteHeader(exchange.StatusCode)
+	}
+	io.WriteString(w, exchange.Response)
+}
+
+// Action returns the robot action of a request body: the action attribute of the first element carrying
+// one, which is the root element for getRootZone and the <zone> element otherwise.
+func Action(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// requests only contain ASCII, whatever encoding they declare
+		return input, nil
+	}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "action" {
+					return attr.Value
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package robottest
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// post sends body to url and returns the status code and body of the response.
+func post(t *testing.T, client *http.Client, url string, body string) (int, string) {
+	t.Helper()
+	response, err := client.Post(url, "application/xml", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("reading response failed: %v", err)
+	}
+	return response.StatusCode, string(data)
+}
+
+const getZoneRequest = `<?xml version="1.0" encoding="ISO-8859-1"?>` + "\n" +
+	`<zoneRequest><zone name="example.com" action="GETZONE" ddnskey="secret"></zone></zoneRequest>`
+
+func TestFixtures(t *testing.T) {
+	fixtures := Fixtures()
+	actions := make(map[string]bool)
+	for name, exchange := range fixtures {
+		if exchange.Action == "" || exchange.Response == "" {
+			t.Errorf("fixture %s lacks action or response", name)
+		}
+		if exchange.Request != "" && Action([]byte(exchange.Request)) != exchange.Action {
+			t.Errorf("fixture %s records a %s request for action %s", name, Action([]byte(exchange.Request)), exchange.Action)
+		}
+		if strings.Contains(exchange.Request, "ddnskey=") && !strings.Contains(exchange.Request, `ddnskey="REDACTED"`) {
+			t.Errorf("fixture %s contains an unredacted DDNS key", name)
+		}
+		actions[exchange.Action] = true
+	}
+	for _, action := range []string{"GETZONE", "ADDORUPDATERR", "DELRR", "getRootZone"} {
+		if !actions[action] {
+			t.Errorf("no fixture for action %s", action)
+		}
+	}
+}
+
+func TestAction(t *testing.T) {
+	for body, want := range map[string]string{
+		getZoneRequest: "GETZONE",
+		`<zoneRequest action="getRootZone" ddnskey="k"><hostname>www.example.com</hostname></zoneRequest>`: "getRootZone",
+		`<zoneRequest></zoneRequest>`: "",
+		`not xml`:                     "",
+	} {
+		if got := Action([]byte(body)); got != want {
+			t.Errorf("Action(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer(
+		Exchange{Action: "GETZONE", Response: "first"},
+		Exchange{Action: "GETZONE", Response: "second", StatusCode: http.StatusServiceUnavailable},
+	)
+	defer server.Close()
+
+	for i, want := range []struct {
+		code int
+		body string
+	}{
+		{http.StatusOK, "first"},
+		{http.StatusServiceUnavailable, "second"},
+		{http.StatusServiceUnavailable, "second"}, // the last exchange is reused
+	} {
+		code, body := post(t, server.Client(), server.URL, getZoneRequest)
+		if code != want.code || body != want.body {
+			t.Errorf("request %d answered with %d %q, want %d %q", i, code, body, want.code, want.body)
+		}
+	}
+
+	code, _ := post(t, server.Client(), server.URL, `<zoneRequest><zone action="DELRR"></zone></zoneRequest>`)
+	if code != http.StatusNotImplemented {
+		t.Errorf("request without exchange answered with %d, want %d", code, http.StatusNotImplemented)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 4 || requests[0].Action != "GETZONE" || requests[0].Request != getZoneRequest || requests[3].Action != "DELRR" {
+		t.Errorf("Requests() = %v", requests)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	server := NewServer(Fixtures()["getzone"])
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: &Recorder{Transport: server.Client().Transport, Dir: dir}}
+	code, body := post(t, client, server.URL, getZoneRequest)
+	if code != http.StatusOK || body != Fixtures()["getzone"].Response {
+		t.Fatalf("recorded request answered with %d %q", code, body)
+	}
+
+	recorded, err := Load(os.DirFS(dir), "*.json")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	exchange, ok := recorded["001-getzone"]
+	if !ok {
+		t.Fatalf("Load() = %v, want fixture 001-getzone", recorded)
+	}
+	if exchange.Action != "GETZONE" || exchange.StatusCode != http.StatusOK || exchange.Response != body {
+		t.Errorf("recorded exchange = %+v", exchange)
+	}
+	if strings.Contains(exchange.Request, "secret") || !strings.Contains(exchange.Request, `ddnskey="REDACTED"`) {
+		t.Errorf("recorded request does not redact the DDNS key: %s", exchange.Request)
+	}
+}
@@ -0,0 +1,80 @@
+package libdns_kyberio
+
+import "strings"
+
+// rrsetKey identifies an RRset: the records of a zone sharing host and type, both compared case-insensitively.
+type rrsetKey struct {
+	host  string
+	rtype string
+}
+
+// keyOf returns the RRset a record belongs to.
+func keyOf(record ResourceRecord) rrsetKey {
+	return rrsetKey{host: strings.ToLower(record.Host), rtype: strings.ToUpper(record.Type)}
+}
+
+// rrset holds the desired and the current records of one RRset.
+type rrset struct {
+	key     rrsetKey
+	desired []ResourceRecord
+	current []ResourceRecord
+}
+
+// groupRRsets groups the desired records by RRset, in the order the RRsets first appear in desired, and
+// collects the current records of each RRset. Repeated desired records are only kept once. RRsets without
+// desired records are not returned.
+func groupRRsets(desired []ResourceRecord, current []ResourceRecord) []*rrset {
+	var sets []*rrset
+	byKey := make(map[rrsetKey]*rrset)
+	for _, record := range desired {
+		key := keyOf(record)
+		set, ok := byKey[key]
+		if !ok {
+			set = &rrset{key: key}
+			byKey[key] = set
+			sets = append(sets, set)
+		}
+		if !containsRecord(set.desired, record) {
+			set.desired = append(set.desired, record)
+		}
+	}
+	for _, record := range current {
+		if set, ok := byKey[keyOf(record)]; ok {
+			set.current = append(set.current, record)
+		}
+	}
+	return sets
+}
+
+// missing returns the desired records that are not current.
+func (s *rrset) missing() []ResourceRecord {
+	var missing []ResourceRecord
+	for _, record := range s.desired {
+		if !containsRecord(s.current, record) {
+			missing = append(missing, record)
+		}
+	}
+	return missing
+}
+
+// present returns the desired records that are current already.
+func (s *rrset) present() []ResourceRecord {
+	var present []ResourceRecord
+	for _, record := range s.desired {
+		if containsRecord(s.current, record) {
+			present = append(present, record)
+		}
+	}
+	return present
+}
+
+// extra returns the current records that are not desired.
+func (s *rrset) extra() []ResourceRecord {
+	var extra []ResourceRecord
+	for _, record := range s.current {
+		if !containsRecord(s.desired, record) {
+			extra = append(extra, ResourceRecord{Host: record.Host, Type: record.Type, Value: record.Value, Class: record.Class})
+		}
+	}
+	return extra
+}
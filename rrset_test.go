@@ -0,0 +1,204 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+// zoneExchange answers GETZONE with an export of example.com holding the given <rr> elements.
+func zoneExchange(records string) robottest.Exchange {
+	return robottest.Exchange{
+		Action:   actionGetZone,
+		Response: `<zoneRequest status="ok"><zone name="example.com"><soa mttl="3600"></soa>` + records + `</zone></zoneRequest>`,
+	}
+}
+
+// aRecords returns A records of www with the given addresses.
+func aRecords(addresses ...string) []libdns.Record {
+	var records []libdns.Record
+	for _, address := range addresses {
+		records = append(records, libdns.RR{Name: "www", Type: "A", Data: address})
+	}
+	return records
+}
+
+// values returns the values of records.
+func values(records []ResourceRecord) []string {
+	var result []string
+	for _, record := range records {
+		result = append(result, record.Value)
+	}
+	return result
+}
+
+func TestSetRecordsShrinksRRset(t *testing.T) {
+	p, server := newTestProvider(t,
+		zoneExchange(`<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.2"></rr><rr host="www" type="A" value="192.0.2.3"></rr>`),
+		robottest.Exchange{Action: actionDeleteRR, Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.3" performedAction="deleted"></rr></zoneRequest>`},
+	)
+
+	records, err := p.SetRecords(context.Background(), testZone, aRecords("192.0.2.1", "192.0.2.2"))
+	if err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("SetRecords() = %v, want the two remaining records", records)
+	}
+
+	if writes := sentRequests(t, server, actionAddOrUpdateRR); len(writes) != 0 {
+		t.Errorf("sent ADDORUPDATERR %v for values the zone holds already", writes)
+	}
+	deletes := sentRequests(t, server, actionDeleteRR)
+	if len(deletes) != 1 || len(deletes[0].Records) != 1 || deletes[0].Records[0].Value != "192.0.2.3" {
+		t.Errorf("sent DELRR %v, want a single one for 192.0.2.3", deletes)
+	}
+}
+
+func TestSetRecordsGrowsRRset(t *testing.T) {
+	p, server := newTestProvider(t,
+		zoneExchange(`<rr host="www" type="A" value="192.0.2.1"></rr><rr host="www" type="A" value="192.0.2.2"></rr>`),
+		robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="ok"><rr host="www" type="A" value="192.0.2.3" performedAction="added"></rr></zoneRequest>`},
+	)
+
+	if _, err := p.SetRecords(context.Background(), testZone, aRecords("192.0.2.1", "192.0.2.2", "192.0.2.3")); err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+
+	if deletes := sentRequests(t, server, actionDeleteRR); len(deletes) != 0 {
+		t.Errorf("sent DELRR %v, want none", deletes)
+	}
+	writes := sentRequests(t, server, actionAddOrUpdateRR)
+	if len(writes) != 1 {
+		t.Fatalf("sent %d ADDORUPDATERR requests, want 1", len(writes))
+	}
+	if got := values(writes[0].Records); len(got) != 1 || got[0] != "192.0.2.3" || !writes[0].Records[0].KeepExisting {
+		t.Errorf("sent ADDORUPDATERR %+v, want 192.0.2.3 added next to the other values", writes[0].Records)
+	}
+}
+
+func TestSetRecordsTTLRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	p, server := newTestProvider(t,
+		fixture(t, "getzone"),
+		robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="ok" zone="example.com"><rr host="www" type="A" value="192.0.2.1" ttl="600" performedAction="updated"></rr></zoneRequest>`},
+		zoneExchange(`<rr host="www" type="A" value="192.0.2.1" ttl="600"></rr>`),
+	)
+
+	set, err := p.SetRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: 600 * time.Second}})
+	if err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if len(set) != 1 || set[0].RR().TTL != 600*time.Second {
+		t.Errorf("SetRecords() = %v, want TTL 10m", set)
+	}
+
+	writes := sentRequests(t, server, actionAddOrUpdateRR)
+	if len(writes) != 1 || len(writes[0].Records) != 1 || writes[0].Records[0].TTL != 600 {
+		t.Fatalf("sent ADDORUPDATERR %+v, want www A with ttl 600", writes)
+	}
+
+	records, err := p.GetRecords(ctx, testZone)
+	if err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].RR().TTL != 600*time.Second {
+		t.Errorf("GetRecords() = %v, want TTL 10m", records)
+	}
+}
+
+func TestSetRecordsUnchanged(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"))
+
+	results, err := p.SetRecordsWithResults(context.Background(), testZone, aRecords("192.0.2.1"))
+	if err != nil {
+		t.Fatalf("SetRecordsWithResults() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Action != ActionUnchanged || results[0].Record.RR().Data != "192.0.2.1" {
+		t.Errorf("SetRecordsWithResults() = %+v, want www A 192.0.2.1 unchanged", results)
+	}
+	records, err := p.SetRecords(context.Background(), testZone, aRecords("192.0.2.1"))
+	if err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("SetRecords() = %v, want the unchanged record", records)
+	}
+	if got := actions(server); !slices.Equal(got, []string{actionGetZone, actionGetZone}) {
+		t.Errorf("sent %v, want only %s for a record the zone holds already", got, actionGetZone)
+	}
+}
+
+func TestSetRecordsTTLOnly(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+		Action:   actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok"><rr host="www" type="AAAA" value="2001:db8::1" ttl="600" performedAction="updated"></rr></zoneRequest>`,
+	})
+
+	results, err := p.SetRecordsWithResults(context.Background(), testZone, []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "www", Type: "AAAA", Data: "2001:db8::1", TTL: 600 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("SetRecordsWithResults() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Action != ActionUnchanged || results[1].Action != ActionUpdated || results[1].Record.RR().TTL != 600*time.Second {
+		t.Errorf("SetRecordsWithResults() = %+v, want the A record unchanged and the AAAA record updated to 10m", results)
+	}
+
+	writes := sentRequests(t, server, actionAddOrUpdateRR)
+	if len(writes) != 1 {
+		t.Fatalf("sent %d ADDORUPDATERR requests, want 1", len(writes))
+	}
+	if records := writes[0].Records; len(records) != 1 || records[0].Type != "AAAA" || records[0].TTL != 600 {
+		t.Errorf("sent ADDORUPDATERR %+v, want only the AAAA record with ttl 600", records)
+	}
+}
+
+func TestSetRecordsInputOrder(t *testing.T) {
+	// the robot reports the written records in another order than they were sent
+	p, _ := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+		Action: actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok">` +
+			`<rr host="c" type="A" value="192.0.2.3" performedAction="added"></rr>` +
+			`<rr host="www" type="AAAA" value="2001:db8::2" performedAction="updated"></rr>` +
+			`<rr host="a" type="A" value="192.0.2.1" performedAction="added"></rr>` +
+			`</zoneRequest>`,
+	}, fixture(t, "delrr"))
+
+	input := []libdns.Record{
+		libdns.RR{Name: "a", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "www", Type: "AAAA", Data: "2001:db8::2"},
+		libdns.RR{Name: "c", Type: "A", Data: "192.0.2.3"},
+	}
+	results, err := p.SetRecordsWithResults(context.Background(), testZone, input)
+	if err != nil {
+		t.Fatalf("SetRecordsWithResults() error = %v", err)
+	}
+	if len(results) < len(input) {
+		t.Fatalf("SetRecordsWithResults() = %+v, want a result per input record", results)
+	}
+	for i, record := range input {
+		if got := results[i].Record; got.Name != record.RR().Name || got.Type != record.RR().Type || got.Data != record.RR().Data {
+			t.Errorf("result %d = %v, want %v", i, got, record)
+		}
+	}
+
+	set, err := p.SetRecords(context.Background(), testZone, input)
+	if err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if len(set) != len(input) {
+		t.Fatalf("SetRecords() = %v, want the %d input records", set, len(input))
+	}
+	for i, record := range input {
+		if set[i].RR().Data != record.RR().Data {
+			t.Errorf("SetRecords() record %d = %v, want %v", i, set[i], record)
+		}
+	}
+}
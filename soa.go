@@ -0,0 +1,28 @@
+package libdns_kyberio
+
+import "context"
+
+// getSOA returns the SOA values of the zone.
+func (p *Provider) getSOA(ctx context.Context, ddnsKey string, zoneName string) (SOA, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return SOA{}, err
+	}
+	return zoneExport.soa, nil
+}
+
+// ZoneInfo holds the zone properties reported by the robot along with the records.
+type ZoneInfo struct {
+	Reseller string // Reseller owning the zone
+	DNSSEC   bool   // Whether DNSSEC is active for the zone
+	SOA      SOA    // SOA values of the zone
+}
+
+// getZoneInfo returns the properties of the zone.
+func (p *Provider) getZoneInfo(ctx context.Context, ddnsKey string, zoneName string) (ZoneInfo, error) {
+	zoneExport, err := p.zone(ctx, ddnsKey, zoneName)
+	if err != nil {
+		return ZoneInfo{}, err
+	}
+	return ZoneInfo{Reseller: zoneExport.reseller, DNSSEC: zoneExport.dnssec, SOA: zoneExport.soa}, nil
+}
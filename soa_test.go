@@ -0,0 +1,18 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetSOA(t *testing.T) {
+	p, _ := newTestProvider(t, fixture(t, "getzone"))
+
+	soa, err := p.GetSOA(context.Background(), testZone)
+	if err != nil {
+		t.Fatalf("GetSOA() error = %v", err)
+	}
+	if want := (SOA{Refresh: 86400, Retry: 7200, Expire: 3600000, MTTL: 3600}); soa != want {
+		t.Errorf("GetSOA() = %+v, want %+v", soa, want)
+	}
+}
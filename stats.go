@@ -0,0 +1,55 @@
+package libdns_kyberio
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Stats is a snapshot of the cumulative write activity of a Provider.
+type Stats struct {
+	Added   uint64 // Records reported as added
+	Updated uint64 // Records reported as updated
+	Deleted uint64 // Records reported as deleted
+	Failed  uint64 // ADDORUPDATERR and DELRR requests that failed
+}
+
+// counters holds the live counters behind Stats. It is safe for concurrent use.
+type counters struct {
+	added   atomic.Uint64
+	updated atomic.Uint64
+	deleted atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// count adds the outcome of a write request to the counters. Requests rejected before they are sent,
+// for invalid records or for their size, are not counted as failed.
+func (c *counters) count(records []ResourceRecord, err error) {
+	if errors.Is(err, ErrInvalidRecord) || errors.Is(err, ErrRequestTooLarge) {
+		return
+	}
+	if err != nil {
+		c.failed.Add(1)
+		return
+	}
+	for _, record := range records {
+		switch record.PerformedAction {
+		case ActionAdded:
+			c.added.Add(1)
+		case ActionUpdated:
+			c.updated.Add(1)
+		case ActionDeleted:
+			c.deleted.Add(1)
+		}
+	}
+}
+
+// Stats returns the number of records added, updated and deleted and the number of failed write
+// requests since the provider was created.
+func (p *Provider) Stats() Stats {
+	return Stats{
+		Added:   p.stats.added.Load(),
+		Updated: p.stats.updated.Load(),
+		Deleted: p.stats.deleted.Load(),
+		Failed:  p.stats.failed.Load(),
+	}
+}
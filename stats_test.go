@@ -0,0 +1,44 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestProvider(t,
+		zoneExchange(""),
+		fixture(t, "addorupdaterr"),
+		robottest.Exchange{Action: actionAddOrUpdateRR, Response: `<zoneRequest status="denied"></zoneRequest>`},
+	)
+	p.MaxRequestBytes = 1 << 10
+
+	if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}}); err != nil {
+		t.Fatalf("AppendRecords() error = %v", err)
+	}
+	if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2"}}); !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("AppendRecords() of an invalid record error = %v, want %v", err, ErrInvalidRecord)
+	}
+	var large []libdns.Record
+	for range 50 {
+		large = append(large, libdns.RR{Name: "large", Type: "TXT", Data: "a value that makes the request too large"})
+	}
+	if _, err := p.AppendRecords(ctx, testZone, large); !errors.Is(err, ErrRequestTooLarge) {
+		t.Fatalf("AppendRecords() of a large batch error = %v, want %v", err, ErrRequestTooLarge)
+	}
+	if stats := p.Stats(); stats.Failed != 0 {
+		t.Errorf("Stats().Failed = %d after requests rejected before sending, want 0", stats.Failed)
+	}
+
+	if _, err := p.AppendRecords(ctx, testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.3"}}); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("AppendRecords() error = %v, want %v", err, ErrAuthFailed)
+	}
+	if got, want := p.Stats(), (Stats{Added: 1, Failed: 1}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package libdns_kyberio
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Length limits of domain names, RFC 1035 section 2.3.4.
+const (
+	maxLabelLength = 63
+	maxNameLength  = 255 // in wire format, including the length octets and the root label
+)
+
+// valueRequired lists the record types that are invalid without a value.
+var valueRequired = map[string]bool{
+	"A":      true,
+	"AAAA":   true,
+	"CAA":    true,
+	"CNAME":  true,
+	"DNAME":  true,
+	"DNSKEY": true,
+	"DS":     true,
+	"HTTPS":  true,
+	"MX":     true,
+	"NS":     true,
+	"PTR":    true,
+	"SRV":    true,
+	"SVCB":   true,
+	"TLSA":   true,
+}
+
+// validateWrite checks records before they are sent in an ADDORUPDATERR request to zoneName. It returns an
+// error wrapping ErrInvalidRecord for the first invalid record, naming the violated limit where there is one,
+// since the robot rejects such records without saying why. Empty values are permitted on the delete path,
+// where they select records by name and type.
+func validateWrite(records []ResourceRecord, zoneName string) error {
+	for _, record := range records {
+		if strings.TrimSpace(record.Value) == "" && valueRequired[strings.ToUpper(record.Type)] {
+			return fmt.Errorf("%w: %s record %q has an empty value", ErrInvalidRecord, record.Type, record.Host)
+		}
+		owner := zoneName
+		if record.Host != "" && record.Host != apexHost {
+			owner = record.Host + "." + zoneName
+		}
+		if err := validateName(owner); err != nil {
+			return fmt.Errorf("%w: host %q: %v", ErrInvalidRecord, record.Host, err)
+		}
+		if err := validateValue(record.Type, record.Value); err != nil {
+			return fmt.Errorf("%w: %s record %q: %v", ErrInvalidRecord, record.Type, record.Host, err)
+		}
+		if strings.EqualFold(record.Type, "CNAME") && (record.Host == "" || record.Host == apexHost) {
+			return fmt.Errorf("%w: CNAME record at the zone apex", ErrInvalidRecord)
+		}
+		if !validWildcard(record.Host) {
+			return fmt.Errorf("%w: host %q has a wildcard that is not the leftmost label", ErrInvalidRecord, record.Host)
+		}
+	}
+	return nil
+}
+
+// validateName checks a domain name against the label and name length limits.
+func validateName(name string) error {
+	name = strings.TrimSuffix(name, ".")
+	for _, label := range strings.Split(name, ".") {
+		if len(label) > maxLabelLength {
+			return fmt.Errorf("label %q is %d bytes, the limit is %d", label, len(label), maxLabelLength)
+		}
+	}
+	if length := len(name) + 2; length > maxNameLength {
+		return fmt.Errorf("name is %d bytes in wire format, the limit is %d", length, maxNameLength)
+	}
+	return nil
+}
+
+// validateValue checks that addresses parse as addresses of the record type and that the names in a value
+// keep the length limits. Values of other types are left to the robot.
+func validateValue(rtype string, value string) error {
+	value = strings.TrimSpace(value)
+	switch strings.ToUpper(rtype) {
+	case "A":
+		if addr, err := netip.ParseAddr(value); err != nil || !addr.Is4() {
+			return fmt.Errorf("value %q is not an IPv4 address", value)
+		}
+	case "AAAA":
+		if addr, err := netip.ParseAddr(value); err != nil || !addr.Is6() {
+			return fmt.Errorf("value %q is not an IPv6 address", value)
+		}
+	case "CNAME", "NS", "PTR", "DNAME":
+		return validateName(value)
+	case "MX":
+		// <preference> <exchange>
+		if fields := strings.Fields(value); len(fields) == 2 {
+			return validateName(fields[1])
+		}
+	case "SRV":
+		// <priority> <weight> <port> <target>
+		if fields := strings.Fields(value); len(fields) == 4 {
+			return validateName(fields[3])
+		}
+	}
+	return nil
+}
+
+// validateCNAMEs checks that writing records to a zone holding existing does not leave a CNAME next to
+// records of another type with the same name. It returns an error wrapping ErrInvalidRecord for the first
+// conflict.
+func validateCNAMEs(records []ResourceRecord, existing []ResourceRecord) error {
+	types := make(map[string]map[string]bool)
+	for _, record := range append(existing[:len(existing):len(existing)], records...) {
+		host := strings.ToLower(record.Host)
+		if types[host] == nil {
+			types[host] = make(map[string]bool)
+		}
+		types[host][strings.ToUpper(record.Type)] = true
+	}
+	for _, record := range records {
+		host := strings.ToLower(record.Host)
+		if !types[host]["CNAME"] {
+			continue
+		}
+		for rtype := range types[host] {
+			if rtype != "CNAME" {
+				return fmt.Errorf("%w: CNAME record %q conflicts with the %s record of the same name", ErrInvalidRecord, record.Host, rtype)
+			}
+		}
+	}
+	return nil
+}
+
+// validWildcard reports whether a host uses the wildcard label * only as its leftmost label, like * or
+// *.dev. Hosts without a wildcard are valid.
+func validWildcard(host string) bool {
+	rest, _ := strings.CutPrefix(host, "*")
+	if rest != host && rest != "" && !strings.HasPrefix(rest, ".") {
+		return false
+	}
+	return !strings.Contains(rest, "*")
+}
+
+// validateSet checks the records passed to a single set operation against each other. A name may either hold
+// one CNAME record or records of other types, so a CNAME next to another record of the same name, or two
+// different CNAMEs, make the desired state invalid. It returns an error wrapping ErrInvalidRecord for the
+// first conflict.
+func validateSet(records []ResourceRecord) error {
+	cnames := make(map[string]ResourceRecord)
+	others := make(map[string]string)
+	for _, record := range records {
+		host := strings.ToLower(record.Host)
+		if !strings.EqualFold(record.Type, "CNAME") {
+			others[host] = strings.ToUpper(record.Type)
+			continue
+		}
+		if cname, ok := cnames[host]; ok && !sameRecord(cname, record) {
+			return fmt.Errorf("%w: conflicting CNAME records %q and %q for %q", ErrInvalidRecord, cname.Value, record.Value, record.Host)
+		}
+		cnames[host] = record
+	}
+	for host, cname := range cnames {
+		if rtype, ok := others[host]; ok {
+			return fmt.Errorf("%w: CNAME record %q conflicts with the %s record of the same name in the input", ErrInvalidRecord, cname.Host, rtype)
+		}
+	}
+	return nil
+}
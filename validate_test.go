@@ -0,0 +1,189 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+func TestSetRecordsConflictingInput(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		records []libdns.Record
+	}{
+		{"CNAME next to an A record", []libdns.Record{
+			libdns.RR{Name: "alias", Type: "CNAME", Data: "www.example.com."},
+			libdns.RR{Name: "alias", Type: "A", Data: "192.0.2.1"},
+		}},
+		{"two CNAMEs for one name", []libdns.Record{
+			libdns.RR{Name: "alias", Type: "CNAME", Data: "a.example.net."},
+			libdns.RR{Name: "Alias", Type: "CNAME", Data: "b.example.net."},
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"))
+
+			if _, err := p.SetRecords(context.Background(), testZone, test.records); !errors.Is(err, ErrInvalidRecord) {
+				t.Fatalf("SetRecords() error = %v, want %v", err, ErrInvalidRecord)
+			}
+			if got := actions(server); len(got) != 1 || got[0] != actionGetZone {
+				t.Errorf("sent %v, want only GETZONE", got)
+			}
+		})
+	}
+}
+
+func TestSetRecordsDuplicateInput(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"), robottest.Exchange{
+		Action:   actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok" zone="example.com"><rr host="alias" type="CNAME" value="a.example.net." performedAction="added"></rr></zoneRequest>`,
+	})
+
+	records, err := p.SetRecords(context.Background(), testZone, []libdns.Record{
+		libdns.RR{Name: "alias", Type: "CNAME", Data: "a.example.net."},
+		libdns.RR{Name: "alias", Type: "CNAME", Data: "a.example.net."},
+	})
+	if err != nil {
+		t.Fatalf("SetRecords() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("SetRecords() = %v, want the record once", records)
+	}
+	writes := sentRequests(t, server, actionAddOrUpdateRR)
+	if len(writes) != 1 || len(writes[0].Records) != 1 {
+		t.Errorf("sent ADDORUPDATERR %+v, want the CNAME once", writes)
+	}
+}
+
+func TestCNAMEConflicts(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		record libdns.Record
+	}{
+		{"at the apex", libdns.RR{Name: "@", Type: "CNAME", Data: "www.example.net."}},
+		{"at the apex, fully qualified", libdns.RR{Name: testZone, Type: "CNAME", Data: "www.example.net."}},
+		{"next to the A and AAAA records of the zone", libdns.RR{Name: "www", Type: "CNAME", Data: "www.example.net."}},
+		{"next to the TXT record of the zone, differently cased", libdns.RR{Name: "_ACME-challenge", Type: "CNAME", Data: "acme.example.net."}},
+	} {
+		for method, call := range map[string]func(ctx context.Context, p *Provider, records []libdns.Record) ([]libdns.Record, error){
+			"AppendRecords": func(ctx context.Context, p *Provider, records []libdns.Record) ([]libdns.Record, error) {
+				return p.AppendRecords(ctx, testZone, records)
+			},
+			"SetRecords": func(ctx context.Context, p *Provider, records []libdns.Record) ([]libdns.Record, error) {
+				return p.SetRecords(ctx, testZone, records)
+			},
+		} {
+			t.Run(method+" "+test.name, func(t *testing.T) {
+				p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"))
+
+				if _, err := call(context.Background(), p, []libdns.Record{test.record}); !errors.Is(err, ErrInvalidRecord) {
+					t.Fatalf("error = %v, want %v", err, ErrInvalidRecord)
+				}
+				if got := actions(server); len(got) != 1 || got[0] != actionGetZone {
+					t.Errorf("sent %v, want only GETZONE", got)
+				}
+			})
+		}
+	}
+}
+
+// longHost returns a host of n bytes made of labels of at most 63 bytes.
+func longHost(n int) string {
+	var labels []string
+	for n > 0 {
+		size := min(n, 63)
+		if n-size == 1 {
+			// leave room for a label after the dot
+			size--
+		}
+		labels = append(labels, strings.Repeat("a", size))
+		n -= size + 1
+	}
+	return strings.Join(labels, ".")
+}
+
+func TestValidateWrite(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		record ResourceRecord
+		valid  bool
+	}{
+		{"A record", ResourceRecord{Host: "www", Type: "A", Value: "192.0.2.1"}, true},
+		{"empty A value", ResourceRecord{Host: "www", Type: "A", Value: ""}, false},
+		{"blank CNAME value", ResourceRecord{Host: "alias", Type: "cname", Value: "  "}, false},
+		{"empty MX value", ResourceRecord{Host: "@", Type: "MX"}, false},
+		{"empty TXT value", ResourceRecord{Host: "_acme-challenge", Type: "TXT"}, true},
+		{"label of 63 bytes", ResourceRecord{Host: strings.Repeat("a", 63), Type: "A", Value: "192.0.2.1"}, true},
+		{"label of 64 bytes", ResourceRecord{Host: strings.Repeat("a", 64), Type: "A", Value: "192.0.2.1"}, false},
+		{"name of 255 bytes", ResourceRecord{Host: longHost(255 - len("example.com") - 3), Type: "A", Value: "192.0.2.1"}, true},
+		{"name of 256 bytes", ResourceRecord{Host: longHost(256 - len("example.com") - 3), Type: "A", Value: "192.0.2.1"}, false},
+		{"CNAME target with a label of 64 bytes", ResourceRecord{Host: "alias", Type: "CNAME", Value: strings.Repeat("a", 64) + ".example.net."}, false},
+		{"MX exchange with a label of 64 bytes", ResourceRecord{Host: "@", Type: "MX", Value: "10 " + strings.Repeat("a", 64) + ".example.net."}, false},
+		{"malformed IPv4 address", ResourceRecord{Host: "www", Type: "A", Value: "192.0.2.256"}, false},
+		{"IPv6 address in an A record", ResourceRecord{Host: "www", Type: "A", Value: "2001:db8::1"}, false},
+		{"IPv6 address", ResourceRecord{Host: "www", Type: "AAAA", Value: "2001:db8::1"}, true},
+		{"malformed IPv6 address", ResourceRecord{Host: "www", Type: "AAAA", Value: "2001:db8::g"}, false},
+		{"IPv4 address in an AAAA record", ResourceRecord{Host: "www", Type: "AAAA", Value: "192.0.2.1"}, false},
+		{"wildcard", ResourceRecord{Host: "*", Type: "A", Value: "192.0.2.1"}, true},
+		{"wildcard below a label", ResourceRecord{Host: "*.dev", Type: "TXT", Value: "wildcard"}, true},
+		{"wildcard in the middle", ResourceRecord{Host: "dev.*", Type: "A", Value: "192.0.2.1"}, false},
+		{"wildcard within a label", ResourceRecord{Host: "*dev", Type: "A", Value: "192.0.2.1"}, false},
+		{"two wildcards", ResourceRecord{Host: "*.*", Type: "A", Value: "192.0.2.1"}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateWrite([]ResourceRecord{test.record}, "example.com")
+			if test.valid && err != nil {
+				t.Errorf("validateWrite() error = %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidRecord) {
+				t.Errorf("validateWrite() error = %v, want %v", err, ErrInvalidRecord)
+			}
+		})
+	}
+}
+
+func TestEmptyValue(t *testing.T) {
+	t.Run("AppendRecords", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"))
+
+		_, err := p.AppendRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "new", Type: "A"}})
+		if !errors.Is(err, ErrInvalidRecord) || !strings.Contains(err.Error(), `"new"`) {
+			t.Fatalf("AppendRecords() error = %v, want %v naming the record", err, ErrInvalidRecord)
+		}
+		if got := count(actions(server), actionAddOrUpdateRR); got != 0 {
+			t.Errorf("sent %d ADDORUPDATERR requests, want none", got)
+		}
+	})
+
+	t.Run("DeleteRecords", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"), deletedExchange)
+
+		// an empty value selects the records by name and type
+		if _, err := p.DeleteRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A"}}); err != nil {
+			t.Fatalf("DeleteRecords() error = %v", err)
+		}
+		if got := sentDeletes(t, server); len(got) != 1 || got[0] != "192.0.2.1" {
+			t.Errorf("sent DELRR for %q, want 192.0.2.1", got)
+		}
+	})
+}
+
+func TestAppendRecordsOutsideLimits(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "addorupdaterr"))
+
+	_, err := p.AppendRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: strings.Repeat("a", 64), Type: "A", Data: "192.0.2.1"}})
+	if !errors.Is(err, ErrInvalidRecord) || !strings.Contains(err.Error(), "the limit is 63") {
+		t.Fatalf("AppendRecords() error = %v, want %v naming the label limit", err, ErrInvalidRecord)
+	}
+	_, err = p.AppendRecords(context.Background(), testZone, []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2"}})
+	if !errors.Is(err, ErrInvalidRecord) || !strings.Contains(err.Error(), "not an IPv4 address") {
+		t.Fatalf("AppendRecords() error = %v, want %v for the malformed address", err, ErrInvalidRecord)
+	}
+	if got := count(actions(server), actionAddOrUpdateRR); got != 0 {
+		t.Errorf("sent %d ADDORUPDATERR requests, want none", got)
+	}
+}
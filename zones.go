@@ -0,0 +1,211 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// Strategies of ZoneMatch.
+const (
+	ZoneMatchLongest  = "longest"
+	ZoneMatchShortest = "shortest"
+)
+
+// zoneView maps record names between the zone passed by the caller and the zone managed by the robot.
+// For a caller zone a.b.example.com managed as example.com, the record www becomes www.a.b.
+type zoneView struct {
+	zone   string // Zone managed by the robot
+	prefix string // Caller zone relative to the robot zone, empty if they are the same
+}
+
+// resolveZone returns the view of the zone passed by the caller, or of DefaultZone if it is empty. If
+// ResolveZone is set, the zone managing the name is looked up with getRootZone, otherwise the zone is
+// used as given.
+func (p *Provider) resolveZone(ctx context.Context, ddnsKey string, zone string) (zoneView, error) {
+	zone, err := p.zoneOrDefault(zone)
+	if err != nil {
+		return zoneView{}, err
+	}
+	if !p.ResolveZone {
+		return zoneView{zone: zone}, nil
+	}
+
+	name := strings.TrimSuffix(zone, ".")
+	key := zoneCacheKey{ddnsKey: ddnsKey, zoneName: name}
+	p.cacheMu.Lock()
+	rootZone, ok := p.rootZones[key]
+	p.cacheMu.Unlock()
+
+	if !ok {
+		rootZone, ok = p.matchZone(name)
+	}
+	if ok {
+		return viewOf(name, rootZone)
+	}
+
+	rootZone, err = p.getRootZone(ctx, ddnsKey, name)
+	if err != nil {
+		return zoneView{}, err
+	}
+	view, err := viewOf(name, rootZone)
+	if err != nil {
+		return zoneView{}, err
+	}
+
+	p.cacheMu.Lock()
+	if p.rootZones == nil {
+		p.rootZones = make(map[zoneCacheKey]string)
+	}
+	p.rootZones[key] = rootZone
+	p.cacheMu.Unlock()
+	return view, nil
+}
+
+// viewOf returns the view of the caller zone name managed as rootZone. Names are compared
+// case-insensitively, like in DNS, so the robot may report the zone in any case. It fails with an error
+// wrapping ErrZoneNotFound if name does not lie inside rootZone.
+func viewOf(name string, rootZone string) (zoneView, error) {
+	lowerName := strings.ToLower(strings.TrimSuffix(name, "."))
+	lowerZone := strings.ToLower(strings.TrimSuffix(rootZone, "."))
+	switch {
+	case lowerName == lowerZone:
+		return zoneView{zone: rootZone}, nil
+	case strings.HasSuffix(lowerName, "."+lowerZone):
+		return zoneView{zone: rootZone, prefix: strings.TrimSuffix(lowerName, "."+lowerZone)}, nil
+	}
+	return zoneView{}, fmt.Errorf("%w: %s is not inside zone %s reported for it", ErrZoneNotFound, name, rootZone)
+}
+
+// matchZone returns the zone among Zones that contains name, choosing the longest or shortest suffix match
+// according to ZoneMatch. It reports false if no zone matches.
+func (p *Provider) matchZone(name string) (string, bool) {
+	var match string
+	for _, zone := range p.Zones {
+		zone = asciiName(strings.TrimSuffix(zone, "."))
+		if zone == "" || (!strings.EqualFold(name, zone) && !strings.HasSuffix(strings.ToLower(name), "."+strings.ToLower(zone))) {
+			continue
+		}
+		if match == "" || (p.ZoneMatch == ZoneMatchShortest) == (len(zone) < len(match)) {
+			match = zone
+		}
+	}
+	return match, match != ""
+}
+
+// zoneOrDefault returns zone, or DefaultZone if zone is empty, in A-label form. It fails with ErrNoZone if
+// both are empty.
+func (p *Provider) zoneOrDefault(zone string) (string, error) {
+	if zone != "" {
+		return asciiName(zone), nil
+	}
+	if p.DefaultZone != "" {
+		return asciiName(p.DefaultZone), nil
+	}
+	return "", ErrNoZone
+}
+
+// callerZone returns the zone passed by the caller.
+func (v zoneView) callerZone() string {
+	if v.prefix == "" {
+		return v.zone
+	}
+	return v.prefix + "." + v.zone
+}
+
+// toRobotName converts a name relative to the caller zone, or fully qualified within it, into a name
+// relative to the robot zone. Fully qualified names outside the caller zone are returned unchanged.
+func (v zoneView) toRobotName(name string) string {
+	if v.prefix == "" {
+		return name
+	}
+	name = relativeHost(asciiName(name), v.callerZone())
+	switch {
+	case name == apexHost:
+		return v.prefix
+	case strings.HasSuffix(name, "."):
+		return name
+	}
+	return name + "." + v.prefix
+}
+
+// fromRobotName converts a name relative to the robot zone into a name relative to the caller zone.
+// It reports false if the name lies outside of the caller zone.
+func (v zoneView) fromRobotName(name string) (string, bool) {
+	if v.prefix == "" {
+		return name, true
+	}
+	if strings.EqualFold(name, v.prefix) {
+		return "@", true
+	}
+	if suffix := "." + v.prefix; len(name) > len(suffix) && strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+		return name[:len(name)-len(suffix)], true
+	}
+	return "", false
+}
+
+// toRobot converts the names of records relative to the caller zone into names relative to the robot zone.
+func (v zoneView) toRobot(records []libdns.Record) []libdns.Record {
+	if v.prefix == "" {
+		return records
+	}
+	converted := make([]libdns.Record, 0, len(records))
+	for _, record := range records {
+		rr := record.RR()
+		rr.Name = v.toRobotName(rr.Name)
+		converted = append(converted, rr)
+	}
+	return converted
+}
+
+// fromRobot converts the names of records relative to the robot zone into names relative to the caller
+// zone and drops records outside of the caller zone.
+func (v zoneView) fromRobot(records []libdns.Record) []libdns.Record {
+	if v.prefix == "" {
+		return records
+	}
+	var converted []libdns.Record
+	for _, record := range records {
+		rr := record.RR()
+		name, ok := v.fromRobotName(rr.Name)
+		if !ok {
+			continue
+		}
+		rr.Name = name
+		converted = append(converted, rr)
+	}
+	return converted
+}
+
+// fromRobotResults works like fromRobot for change results.
+func (v zoneView) fromRobotResults(results []ChangeResult) []ChangeResult {
+	if v.prefix == "" {
+		return results
+	}
+	var converted []ChangeResult
+	for _, result := range results {
+		name, ok := v.fromRobotName(result.Record.Name)
+		if !ok {
+			continue
+		}
+		result.Record.Name = name
+		converted = append(converted, result)
+	}
+	return converted
+}
+
+// listZones returns the configured Zones. The robot has no action listing the zones of a key, so without
+// configured zones an error wrapping errors.ErrUnsupported is returned rather than an empty list.
+func (p *Provider) listZones() ([]libdns.Zone, error) {
+	if len(p.Zones) == 0 {
+		return nil, fmt.Errorf("listing zones: %w: the robot has no zone list action; configure Zones to enumerate them", errors.ErrUnsupported)
+	}
+	zones := make([]libdns.Zone, 0, len(p.Zones))
+	for _, zone := range p.Zones {
+		zones = append(zones, libdns.Zone{Name: strings.TrimSuffix(zone, ".") + "."})
+	}
+	return zones, nil
+}
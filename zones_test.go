@@ -0,0 +1,361 @@
+package libdns_kyberio
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dhostx/libdns_kyberio/robottest"
+	"github.com/libdns/libdns"
+)
+
+// rootZoneExchange answers getRootZone with the given zone.
+func rootZoneExchange(zone string) robottest.Exchange {
+	return robottest.Exchange{
+		Action:   actionGetRootZone,
+		Response: `<zoneRequest status="found"><zonename>` + zone + `</zonename></zoneRequest>`,
+	}
+}
+
+func TestResolveZoneCaseInsensitive(t *testing.T) {
+	p, server := newTestProvider(t,
+		rootZoneExchange("Example.COM"),
+		fixture(t, "getzone"),
+		robottest.Exchange{
+			Action:   actionAddOrUpdateRR,
+			Response: `<zoneRequest status="ok" zone="Example.COM"><rr host="www.a" type="A" value="192.0.2.2" performedAction="added"></rr></zoneRequest>`,
+		},
+	)
+	p.ResolveZone = true
+
+	records, err := p.AppendRecords(context.Background(), "A.example.com.", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}})
+	if err != nil {
+		t.Fatalf("AppendRecords() error = %v", err)
+	}
+	if len(records) != 1 || records[0].RR().Name != "www" {
+		t.Errorf("AppendRecords() = %v, want www relative to the caller zone", records)
+	}
+
+	var write string
+	for _, request := range server.Requests() {
+		if request.Action == actionAddOrUpdateRR {
+			write = request.Request
+		}
+	}
+	if !strings.Contains(write, `host="www.a"`) {
+		t.Errorf("ADDORUPDATERR request = %s, want host www.a", write)
+	}
+}
+
+func TestResolveZoneOutsideReportedZone(t *testing.T) {
+	p, server := newTestProvider(t, rootZoneExchange("example.org"), fixture(t, "getzone"), fixture(t, "addorupdaterr"))
+	p.ResolveZone = true
+
+	_, err := p.AppendRecords(context.Background(), "a.example.com.", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}})
+	if !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("AppendRecords() error = %v, want %v", err, ErrZoneNotFound)
+	}
+	if got := actions(server); len(got) != 1 || got[0] != actionGetRootZone {
+		t.Errorf("sent %v, want only %s", got, actionGetRootZone)
+	}
+}
+
+func TestFromRobotNameCaseInsensitive(t *testing.T) {
+	view := zoneView{zone: "example.com", prefix: "a"}
+	for _, test := range []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"A", "@", true},
+		{"www.A", "www", true},
+		{"WWW.a", "WWW", true},
+		{"ba", "", false},
+		{"www.b", "", false},
+	} {
+		got, ok := view.fromRobotName(test.name)
+		if got != test.want || ok != test.ok {
+			t.Errorf("fromRobotName(%q) = %q, %v, want %q, %v", test.name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestResolveZoneNestedNames(t *testing.T) {
+	const zone = "a.b.example.com"
+	written := robottest.Exchange{
+		Action:   actionAddOrUpdateRR,
+		Response: `<zoneRequest status="ok"><rr host="www.a.b" type="A" value="192.0.2.2" performedAction="added"></rr></zoneRequest>`,
+	}
+	deleted := robottest.Exchange{
+		Action:   actionDeleteRR,
+		Response: `<zoneRequest status="ok"><rr host="www.a.b" type="A" value="192.0.2.1" performedAction="deleted"></rr><rr host="www.a.b" type="TXT" value="note" performedAction="deleted"></rr></zoneRequest>`,
+	}
+	for _, test := range []struct {
+		method string
+		action string
+		call   func(ctx context.Context, p *Provider, name string) ([]libdns.Record, error)
+	}{
+		{"AppendRecords", actionAddOrUpdateRR, func(ctx context.Context, p *Provider, name string) ([]libdns.Record, error) {
+			return p.AppendRecords(ctx, zone, []libdns.Record{libdns.RR{Name: name, Type: "A", Data: "192.0.2.2"}})
+		}},
+		{"DeleteName", actionDeleteRR, func(ctx context.Context, p *Provider, name string) ([]libdns.Record, error) {
+			return p.DeleteName(ctx, zone, name)
+		}},
+		{"SwapRecord", actionAddOrUpdateRR, func(ctx context.Context, p *Provider, name string) ([]libdns.Record, error) {
+			rr, err := p.SwapRecord(ctx, zone, name, "A", "192.0.2.2")
+			return []libdns.Record{rr}, err
+		}},
+		{"CompareAndSwapRecord", actionAddOrUpdateRR, func(ctx context.Context, p *Provider, name string) ([]libdns.Record, error) {
+			rr, err := p.CompareAndSwapRecord(ctx, zone, name, "A", "192.0.2.1", "192.0.2.2")
+			return []libdns.Record{rr}, err
+		}},
+		{"UpdateDynamicIP", actionAddOrUpdateRR, func(ctx context.Context, p *Provider, name string) ([]libdns.Record, error) {
+			return p.UpdateDynamicIP(ctx, zone, name, "192.0.2.2")
+		}},
+		{"ReplaceRecord", actionAddOrUpdateRR, func(ctx context.Context, p *Provider, name string) ([]libdns.Record, error) {
+			rr, err := p.ReplaceRecord(ctx, zone, name, "A", "192.0.2.1", "192.0.2.2")
+			return []libdns.Record{rr}, err
+		}},
+	} {
+		for _, name := range []string{"www", "www.a.b.example.com."} {
+			t.Run(test.method+"/"+name, func(t *testing.T) {
+				p, server := newTestProvider(t,
+					rootZoneExchange("example.com"),
+					zoneExchange(`<rr host="www.a.b" type="A" value="192.0.2.1"></rr><rr host="www.a.b" type="TXT" value="note"></rr>`),
+					written, deleted,
+				)
+				p.ResolveZone = true
+
+				records, err := test.call(context.Background(), p, name)
+				if err != nil {
+					t.Fatalf("%s() error = %v", test.method, err)
+				}
+				if len(records) == 0 {
+					t.Fatalf("%s() returned no records", test.method)
+				}
+				for _, record := range records {
+					if got := record.RR().Name; got != "www" {
+						t.Errorf("%s() returned name %q, want www relative to %s", test.method, got, zone)
+					}
+				}
+
+				requests := sentRequests(t, server, test.action)
+				if len(requests) != 1 || len(requests[0].Records) == 0 {
+					t.Fatalf("sent %s %+v, want a single request with records", test.action, requests)
+				}
+				for _, record := range requests[0].Records {
+					if record.Host != "www.a.b" {
+						t.Errorf("sent %s host %q, want www.a.b", test.action, record.Host)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCountRecordsResolveZone(t *testing.T) {
+	p, _ := newTestProvider(t,
+		rootZoneExchange("example.com"),
+		zoneExchange(`<rr host="www.a.b" type="A" value="192.0.2.1"></rr><rr host="a.b" type="TXT" value="apex"></rr><rr host="www" type="A" value="192.0.2.2"></rr>`),
+	)
+	p.ResolveZone = true
+
+	count, err := p.CountRecords(context.Background(), "a.b.example.com.")
+	if err != nil {
+		t.Fatalf("CountRecords() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountRecords() = %d, want the 2 records within a.b.example.com", count)
+	}
+}
+
+func TestToRobotName(t *testing.T) {
+	view := zoneView{zone: "example.com", prefix: "a.b"}
+	for name, want := range map[string]string{
+		"":                     "a.b",
+		"@":                    "a.b",
+		"www":                  "www.a.b",
+		"www.a.b.example.com.": "www.a.b",
+		"www.a.b.example.com":  "www.a.b",
+		"a.b.example.com.":     "a.b",
+		"*.A.B.Example.com.":   "*.a.b",
+		"www.example.com.":     "www.example.com.",
+	} {
+		if got := view.toRobotName(name); got != want {
+			t.Errorf("toRobotName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMatchZone(t *testing.T) {
+	zones := []string{"example.com.", "sub.example.com", "other.example."}
+	for _, test := range []struct {
+		name, match, want string
+	}{
+		{"a.sub.example.com", "", "sub.example.com"},
+		{"a.sub.example.com", ZoneMatchLongest, "sub.example.com"},
+		{"a.sub.example.com", ZoneMatchShortest, "example.com"},
+		{"sub.example.com", ZoneMatchLongest, "sub.example.com"},
+		{"A.SUB.Example.com", "", "sub.example.com"},
+		{"www.example.com", ZoneMatchLongest, "example.com"},
+		{"notsub.example.com", ZoneMatchLongest, "example.com"},
+		{"example.org", "", ""},
+		{"xexample.com", "", ""},
+	} {
+		p := &Provider{Zones: zones, ZoneMatch: test.match}
+		if got, ok := p.matchZone(test.name); got != test.want || ok != (test.want != "") {
+			t.Errorf("matchZone(%q) with ZoneMatch %q = %q, %t, want %q", test.name, test.match, got, ok, test.want)
+		}
+	}
+}
+
+func TestResolveZoneOverlappingZones(t *testing.T) {
+	for _, test := range []struct {
+		match, wantZone, wantHost string
+	}{
+		{ZoneMatchLongest, "sub.example.com", "www.a"},
+		{ZoneMatchShortest, "example.com", "www.a.sub"},
+	} {
+		t.Run(test.match, func(t *testing.T) {
+			p, server := newTestProvider(t, zoneExchange(""), robottest.Exchange{
+				Action:   actionAddOrUpdateRR,
+				Response: `<zoneRequest status="ok"><rr host="` + test.wantHost + `" type="A" value="192.0.2.2" performedAction="added"></rr></zoneRequest>`,
+			})
+			p.ResolveZone = true
+			p.Zones = []string{"example.com", "sub.example.com"}
+			p.ZoneMatch = test.match
+
+			records, err := p.AppendRecords(context.Background(), "a.sub.example.com.", []libdns.Record{libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"}})
+			if err != nil {
+				t.Fatalf("AppendRecords() error = %v", err)
+			}
+			if len(records) != 1 || records[0].RR().Name != "www" {
+				t.Errorf("AppendRecords() = %v, want www relative to the caller zone", records)
+			}
+			if count(actions(server), actionGetRootZone) != 0 {
+				t.Errorf("sent %v, want the zone resolved locally", actions(server))
+			}
+			writes := sentRequests(t, server, actionAddOrUpdateRR)
+			if len(writes) != 1 || strings.TrimSuffix(writes[0].Name, ".") != test.wantZone || writes[0].Records[0].Host != test.wantHost {
+				t.Errorf("sent ADDORUPDATERR %+v, want %s in zone %s", writes, test.wantHost, test.wantZone)
+			}
+		})
+	}
+}
+
+func TestResolveZoneNoMatchingZone(t *testing.T) {
+	p, server := newTestProvider(t, rootZoneExchange("example.org"), fixture(t, "getzone"))
+	p.ResolveZone = true
+	p.Zones = []string{"example.com", "sub.example.com"}
+
+	if _, err := p.GetRecords(context.Background(), "a.example.org."); err != nil {
+		t.Fatalf("GetRecords() error = %v", err)
+	}
+	if got := actions(server); len(got) != 2 || got[0] != actionGetRootZone {
+		t.Errorf("sent %v, want %s for a name outside Zones", got, actionGetRootZone)
+	}
+}
+
+func TestRootZoneEndpoint(t *testing.T) {
+	t.Run("separate endpoint", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getzone"))
+		rootServer := robottest.NewServer(fixture(t, "getrootzone"))
+		t.Cleanup(rootServer.Close)
+		p.RootZoneEndpoint = rootServer.URL
+		p.ResolveZone = true
+
+		if zone, err := p.GetRootZone(context.Background(), "www.example.com"); err != nil || zone != "example.com" {
+			t.Fatalf("GetRootZone() = %q, %v, want example.com", zone, err)
+		}
+		if _, err := p.GetRecords(context.Background(), "sub.example.com."); err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+		if got, want := actions(rootServer), []string{actionGetRootZone, actionGetRootZone}; !slices.Equal(got, want) {
+			t.Errorf("root zone endpoint received %v, want %v", got, want)
+		}
+		if got, want := actions(server), []string{actionGetZone}; !slices.Equal(got, want) {
+			t.Errorf("endpoint received %v, want %v", got, want)
+		}
+	})
+
+	t.Run("defaults to Endpoint", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getrootzone"), fixture(t, "getzone"))
+		p.ResolveZone = true
+
+		if _, err := p.GetRecords(context.Background(), "sub.example.com."); err != nil {
+			t.Fatalf("GetRecords() error = %v", err)
+		}
+		if got, want := actions(server), []string{actionGetRootZone, actionGetZone}; !slices.Equal(got, want) {
+			t.Errorf("endpoint received %v, want %v", got, want)
+		}
+	})
+}
+
+func TestPing(t *testing.T) {
+	denied := robottest.Exchange{Action: actionGetRootZone, Response: `<zoneRequest status="denied"></zoneRequest>`}
+	for _, test := range []struct {
+		name          string
+		endpoint      robottest.Exchange
+		rootEndpoint  robottest.Exchange
+		wantErr       error
+		wantRootProbe bool
+	}{
+		{"both accept the key", fixture(t, "getrootzone-notfound"), fixture(t, "getrootzone-notfound"), nil, true},
+		{"write endpoint denies the key", denied, fixture(t, "getrootzone-notfound"), ErrAuthFailed, false},
+		{"root zone endpoint denies the key", fixture(t, "getrootzone-notfound"), denied, ErrAuthFailed, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, server := newTestProvider(t, test.endpoint)
+			rootServer := robottest.NewServer(test.rootEndpoint)
+			t.Cleanup(rootServer.Close)
+			p.RootZoneEndpoint = rootServer.URL
+
+			if err := p.Ping(context.Background()); !errors.Is(err, test.wantErr) {
+				t.Fatalf("Ping() error = %v, want %v", err, test.wantErr)
+			}
+			if got := actions(server); len(got) != 1 {
+				t.Errorf("endpoint received %v, want a single probe", got)
+			}
+			if probed := len(actions(rootServer)) > 0; probed != test.wantRootProbe {
+				t.Errorf("root zone endpoint probed = %v, want %v", probed, test.wantRootProbe)
+			}
+		})
+	}
+
+	t.Run("shared endpoint", func(t *testing.T) {
+		p, server := newTestProvider(t, fixture(t, "getrootzone-notfound"))
+
+		if err := p.Ping(context.Background()); err != nil {
+			t.Fatalf("Ping() error = %v", err)
+		}
+		if got := actions(server); len(got) != 1 {
+			t.Errorf("endpoint received %v, want a single probe", got)
+		}
+	})
+}
+
+func TestListZones(t *testing.T) {
+	p, server := newTestProvider(t, fixture(t, "getzone"), fixture(t, "getrootzone"))
+
+	if _, err := p.ListZones(context.Background()); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ListZones() without Zones error = %v, want %v", err, errors.ErrUnsupported)
+	}
+
+	p.Zones = []string{"example.com", "sub.example.com.", "xn--mnchen-3ya.example"}
+	zones, err := p.ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("ListZones() error = %v", err)
+	}
+	var names []string
+	for _, zone := range zones {
+		names = append(names, zone.Name)
+	}
+	if want := []string{"example.com.", "sub.example.com.", "xn--mnchen-3ya.example."}; !slices.Equal(names, want) {
+		t.Errorf("ListZones() = %q, want %q", names, want)
+	}
+	if got := actions(server); len(got) != 0 {
+		t.Errorf("ListZones() sent %v, want no requests", got)
+	}
+}